@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	attributeType  string
+	attributeFile  string
+	attributeNames []string
+)
+
+// The attribute types, by their lower case name, as accepted by the --type flag
+var attributeTypeNames = map[string]string{
+	"string":  "String",
+	"numeric": "Numeric",
+	"alias":   "Alias",
+}
+
+// attributeCmd represents the attribute command
+var attributeCmd = &cobra.Command{
+	Use:   "attribute",
+	Short: "Manage the element attributes of a dimension and their values",
+}
+
+// attributesCube returns the name of the control cube holding the values of the element attributes of the dimension
+func attributesCube(dimension string) string {
+	return "}ElementAttributes_" + dimension
+}
+
+// attributeUpdate returns the update of the value of the attribute of the element in the dimension's element
+// attributes cube
+func attributeUpdate(dimension, hierarchy, element, attribute string, value any) utils.CellUpdate {
+	cube := attributesCube(dimension)
+	return utils.CellUpdate{
+		Elements: []string{utils.ElementPath(dimension, hierarchy, element), utils.ElementPath(cube, cube, attribute)},
+		Value:    value,
+	}
+}
+
+// resolveAttributes makes sure all the attributes of the hierarchy exist in the dimension, whose attributes are
+// those of the current hierarchy, and refers to them by their defined name. Values of numeric attributes are
+// converted to numbers.
+func resolveAttributes(h, current *utils.Hierarchy, attributeTypes map[string]string) error {
+	defined := make(map[string]string, len(current.Attributes))
+	for _, name := range current.Attributes {
+		defined[utils.NameKey(name)] = name
+	}
+	for i, name := range h.Attributes {
+		attribute, ok := defined[utils.NameKey(name)]
+		if !ok {
+			return utils.NotFoundError("attribute '%s' does not exist in the dimension", name)
+		}
+		h.Attributes[i] = attribute
+		for _, element := range h.Elements {
+			val, ok := element.Attributes[name]
+			if !ok {
+				continue
+			}
+			delete(element.Attributes, name)
+			if s, isString := val.(string); isString && attributeTypes[utils.NameKey(name)] == "Numeric" {
+				number, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return utils.UsageError("invalid value '%s' for numeric attribute '%s' of element '%s'", s, attribute, element.Name)
+				}
+				val = number
+			}
+			element.Attributes[attribute] = val
+		}
+	}
+	return nil
+}
+
+// otherHierarchyElements returns the elements of the hierarchies of the dimension, other than the hierarchy specified,
+// with the values of the attributes specified
+func otherHierarchyElements(dimension, hierarchy string, attributes []string) ([]*utils.HierarchyElement, error) {
+	if hierarchy == "" {
+		hierarchy = dimension
+	}
+	path := "Dimensions" + utils.ODataKey(dimension) + "/Hierarchies?$select=Name&$expand=Elements($select=Name,Attributes)"
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+	if err != nil {
+		return nil, err
+	}
+	var elements []*utils.HierarchyElement
+	hierarchies, _ := data["value"].([]any)
+	for _, rawHierarchy := range hierarchies {
+		h, _ := rawHierarchy.(map[string]any)
+		if utils.NameKey(utils.Stringify(h["Name"])) == utils.NameKey(hierarchy) {
+			continue
+		}
+		list, _ := h["Elements"].([]any)
+		for _, raw := range list {
+			if element, ok := raw.(map[string]any); ok {
+				e := &utils.HierarchyElement{Name: utils.Stringify(element["Name"]), Attributes: make(map[string]any)}
+				for _, name := range attributes {
+					if val, ok := elementAttribute(element, name); ok {
+						e.Attributes[name] = val
+					}
+				}
+				elements = append(elements, e)
+			}
+		}
+	}
+	return elements, nil
+}
+
+// validateAliases makes sure that the values of the alias attributes of the elements, being those of the hierarchy
+// of the dimension as they are to be, uniquely identify an element, meaning no alias value is used by another
+// element, either as its name or as any of its alias values. Like the service does, the names, and alias values, of
+// the elements of all other hierarchies of the dimension are taken into account as well.
+func validateAliases(dimension, hierarchy string, elements []*utils.HierarchyElement, attributes []string, attributeTypes map[string]string) error {
+	var aliases []string
+	for _, name := range attributes {
+		if attributeTypes[utils.NameKey(name)] == "Alias" {
+			aliases = append(aliases, name)
+		}
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+	others, err := otherHierarchyElements(dimension, hierarchy, aliases)
+	if err != nil {
+		return err
+	}
+
+	// Elements of other hierarchies sharing their name with an element of the hierarchy are that same element
+	names := make(map[string]string, len(elements)+len(others))
+	for _, element := range elements {
+		names[utils.NameKey(element.Name)] = element.Name
+	}
+	used := make(map[string]string)
+	for _, element := range others {
+		key := utils.NameKey(element.Name)
+		if _, ok := names[key]; ok {
+			continue
+		}
+		names[key] = element.Name
+		for _, alias := range aliases {
+			if value := utils.Stringify(element.Attributes[alias]); element.Attributes[alias] != nil && value != "" {
+				if _, ok := used[utils.NameKey(value)]; !ok {
+					used[utils.NameKey(value)] = element.Name
+				}
+			}
+		}
+	}
+	var conflicts []string
+	for _, element := range elements {
+		for _, alias := range aliases {
+			value := utils.Stringify(element.Attributes[alias])
+			if element.Attributes[alias] == nil || value == "" {
+				continue
+			}
+			key := utils.NameKey(value)
+			if owner, ok := names[key]; ok && utils.NameKey(owner) != utils.NameKey(element.Name) {
+				conflicts = append(conflicts, fmt.Sprintf("alias '%s' of element '%s' is the name of element '%s'", value, element.Name, owner))
+			} else if owner, ok := used[key]; ok && utils.NameKey(owner) != utils.NameKey(element.Name) {
+				conflicts = append(conflicts, fmt.Sprintf("alias '%s' of element '%s' is also an alias of element '%s'", value, element.Name, owner))
+			} else {
+				used[key] = element.Name
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return utils.ConflictError("alias values are not unique, no changes have been made:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
+
+var attributeListCmd = &cobra.Command{
+	Use:   "list <dimension>",
+	Short: "Get the list of element attributes of a dimension",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := hierarchyPath(args[0], "") + "/ElementAttributes?$select=Name,Type"
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			return utils.DatabaseAPIGet(host, instance, database, user, password, path)
+		}, "Name", "Type")
+	},
+}
+
+var attributeCreateCmd = &cobra.Command{
+	Use:   "create <dimension> <attribute>",
+	Short: "Creates a new element attribute in the dimension",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		typeName, ok := attributeTypeNames[strings.ToLower(attributeType)]
+		if !ok {
+			return utils.UsageError("invalid attribute type '%s', supported types are: String, Numeric and Alias", attributeType)
+		}
+		payload := map[string]any{"Name": args[1], "Type": typeName}
+		data, err := utils.DatabaseAPIPost(host, instance, database, user, password, hierarchyPath(args[0], "")+"/ElementAttributes", payload)
+		if err != nil {
+			return err
+		}
+		return utils.OutputEntity(data)
+	},
+}
+
+var attributeDeleteCmd = &cobra.Command{
+	Use:   "delete <dimension> <attribute>",
+	Short: "Deletes an element attribute, and all its values, from the dimension",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, hierarchyPath(args[0], "")+"/ElementAttributes"+utils.ODataKey(args[1])); err != nil {
+			return err
+		}
+		fmt.Printf("Attribute '%s' has been deleted from dimension '%s'!\n", args[1], args[0])
+		return nil
+	},
+}
+
+var attributeGetCmd = &cobra.Command{
+	Use:   "get <dimension> [element]",
+	Short: "Get the attribute values of an element or, if no element is specified, of all elements",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, types, err := fetchHierarchy(args[0], hierarchyName)
+		if err != nil {
+			return err
+		}
+		attributes := h.Attributes
+		if len(attributeNames) > 0 {
+			selected := utils.NewHierarchy(attributeNames)
+			if err := resolveAttributes(selected, h, types); err != nil {
+				return err
+			}
+			attributes = selected.Attributes
+		}
+
+		if len(args) == 2 {
+			element := h.Element(args[1])
+			if element == nil {
+				return utils.NotFoundError("element '%s' not found in the hierarchy", args[1])
+			}
+			rows := make([]any, len(attributes))
+			for i, name := range attributes {
+				rows[i] = map[string]any{"Attribute": name, "Type": types[utils.NameKey(name)], "Value": element.Attributes[name]}
+			}
+			return utils.OutputRows(rows, "Attribute", "Type", "Value")
+		}
+
+		rows := make([]any, len(h.Elements))
+		for i, element := range h.Elements {
+			row := map[string]any{"Name": element.Name}
+			for _, name := range attributes {
+				row[name] = element.Attributes[name]
+			}
+			rows[i] = row
+		}
+		return utils.OutputRows(rows, append([]string{"Name"}, attributes...)...)
+	},
+}
+
+var attributeSetCmd = &cobra.Command{
+	Use:   "set <dimension> [<element> <attribute> <value>]",
+	Short: "Sets the value of an attribute of an element, or, using --file, the attribute values in a CSV file",
+	Long: `Sets the value of an attribute of an element or, using --file, the attribute values in a CSV file. The CSV
+file requires a header row with an 'Element' column, all other columns holding the values of the attribute with
+the column's name, empty values being left untouched. Before any value is written, the values of alias attributes
+are validated to uniquely identify an element within the dimension, across all its hierarchies.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if attributeFile != "" {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(4)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, hierarchy := args[0], hierarchyName
+		if hierarchy == "" {
+			hierarchy = dimension
+		}
+
+		var values *utils.Hierarchy
+		if attributeFile != "" {
+			file, err := os.Open(attributeFile)
+			if err != nil {
+				return utils.UsageError("unable to open file: %v", err)
+			}
+			defer file.Close()
+			if values, err = utils.ReadAttributeValues(file); err != nil {
+				return err
+			}
+		} else {
+			values = utils.NewHierarchy([]string{args[2]})
+			values.AddElement(args[1], "").Attributes[args[2]] = args[3]
+		}
+
+		current, types, err := fetchHierarchy(dimension, hierarchy)
+		if err != nil {
+			return err
+		}
+		if err := resolveAttributes(values, current, types); err != nil {
+			return err
+		}
+
+		// Apply the new values to the current elements, validating the result before writing any of them
+		var updates []utils.CellUpdate
+		for _, element := range values.Elements {
+			existing := current.Element(element.Name)
+			if existing == nil {
+				return utils.NotFoundError("element '%s' not found in the hierarchy", element.Name)
+			}
+			for _, name := range values.Attributes {
+				val, ok := element.Attributes[name]
+				if !ok {
+					continue
+				}
+				existing.Attributes[name] = val
+				updates = append(updates, attributeUpdate(dimension, hierarchy, existing.Name, name, val))
+			}
+		}
+		if err := validateAliases(dimension, hierarchy, current.Elements, current.Attributes, types); err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			fmt.Println("No attribute values to set.")
+			return nil
+		}
+		if err := utils.UpdateCells(host, instance, database, user, password, attributesCube(dimension), updates); err != nil {
+			return err
+		}
+		fmt.Printf("%d attribute values have been set.\n", len(updates))
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(attributeListCmd)
+	attributeCmd.AddCommand(attributeListCmd)
+
+	addDatabaseFlags(attributeCreateCmd)
+	attributeCreateCmd.Flags().StringVar(&attributeType, "type", "String", "The type of the attribute: String, Numeric or Alias")
+	attributeCmd.AddCommand(attributeCreateCmd)
+
+	addDatabaseFlags(attributeDeleteCmd)
+	attributeCmd.AddCommand(attributeDeleteCmd)
+
+	addDatabaseFlags(attributeGetCmd)
+	addHierarchyFlag(attributeGetCmd)
+	attributeGetCmd.Flags().StringArrayVar(&attributeNames, "attribute", nil, "Only get the values of this attribute, can be repeated")
+	attributeCmd.AddCommand(attributeGetCmd)
+
+	addDatabaseFlags(attributeSetCmd)
+	addHierarchyFlag(attributeSetCmd)
+	attributeSetCmd.Flags().StringVar(&attributeFile, "file", "", "A CSV file with the attribute values to be set")
+	attributeCmd.AddCommand(attributeSetCmd)
+
+	rootCmd.AddCommand(attributeCmd)
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	cellSandbox   string
+	cellFile      string
+	cellMap       []string
+	cellBatchSize int
+)
+
+// cellCmd represents the cell command
+var cellCmd = &cobra.Command{
+	Use:   "cell",
+	Short: "Write values to the cells of a cube",
+}
+
+// cellCube holds the dimensions of a cube and, per dimension, the types of its elements, by their name key, as used
+// to validate the coordinates of, and the values written to, the cells of the cube
+type cellCube struct {
+	name       string
+	dimensions []string
+	elements   []map[string]*utils.HierarchyElement
+}
+
+// fetchCellCube retrieves the dimensions of the cube and the elements of their same-named hierarchies
+func fetchCellCube(name string) (*cellCube, error) {
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes"+utils.ODataKey(name)+"?$select=Name&$expand=Dimensions($select=Name)")
+	if err != nil {
+		return nil, err
+	}
+	cube := &cellCube{name: utils.Stringify(data["Name"]), dimensions: cubeDimensionNames(data)}
+	for _, dimension := range cube.dimensions {
+		data, err := utils.DatabaseAPIGet(host, instance, database, user, password, hierarchyPath(dimension, "")+"/Elements?$select=Name,Type")
+		if err != nil {
+			return nil, err
+		}
+		list, _ := data["value"].([]any)
+		elements := make(map[string]*utils.HierarchyElement, len(list))
+		for _, raw := range list {
+			if element, ok := raw.(map[string]any); ok {
+				name := utils.Stringify(element["Name"])
+				elements[utils.NameKey(name)] = &utils.HierarchyElement{Name: name, Type: utils.Stringify(element["Type"])}
+			}
+		}
+		cube.elements = append(cube.elements, elements)
+	}
+	return cube, nil
+}
+
+// update validates the coordinates, one element for every dimension of the cube, and the value and returns the
+// update writing the value to the cell. The value is written as a string if the element of the last dimension is a
+// string element and as a number otherwise.
+func (c *cellCube) update(coordinates []string, value string) (utils.CellUpdate, error) {
+	if len(coordinates) != len(c.dimensions) {
+		return utils.CellUpdate{}, utils.UsageError("%d elements specified, cube '%s' has %d dimensions", len(coordinates), c.name, len(c.dimensions))
+	}
+	update := utils.CellUpdate{Elements: make([]string, len(coordinates))}
+	var last *utils.HierarchyElement
+	for i, name := range coordinates {
+		element, ok := c.elements[i][utils.NameKey(strings.TrimSpace(name))]
+		if !ok {
+			return utils.CellUpdate{}, utils.NotFoundError("element '%s' not found in dimension '%s'", name, c.dimensions[i])
+		}
+		if element.Type == "Consolidated" {
+			return utils.CellUpdate{}, utils.UsageError("element '%s' of dimension '%s' is a consolidation", element.Name, c.dimensions[i])
+		}
+		update.Elements[i] = utils.ElementPath(c.dimensions[i], c.dimensions[i], element.Name)
+		last = element
+	}
+	if last != nil && last.Type == "String" {
+		update.Value = value
+		return update, nil
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return utils.CellUpdate{}, utils.UsageError("invalid numeric value '%s'", value)
+	}
+	update.Value = number
+	return update, nil
+}
+
+// cellColumns maps the columns in the header of a CSV file to the dimensions of the cube, returning the index of
+// the column for every dimension and the index of the column holding the values. Columns map to the dimension, or
+// the 'Value', with the same name unless mapped otherwise, using 'column=dimension', in the mapping.
+func (c *cellCube) cellColumns(header, mapping []string) ([]int, int, error) {
+	targets := make(map[string]string, len(mapping))
+	for _, m := range mapping {
+		column, target, ok := strings.Cut(m, "=")
+		if !ok {
+			return nil, 0, utils.UsageError("invalid mapping '%s', expected 'column=dimension'", m)
+		}
+		targets[utils.NameKey(column)] = utils.NameKey(target)
+	}
+	indices := make(map[string]int, len(header))
+	for i, column := range header {
+		key := utils.NameKey(column)
+		if target, ok := targets[key]; ok {
+			key = target
+		}
+		indices[key] = i
+	}
+
+	columns := make([]int, len(c.dimensions))
+	for i, dimension := range c.dimensions {
+		index, ok := indices[utils.NameKey(dimension)]
+		if !ok {
+			return nil, 0, utils.UsageError("no column maps to dimension '%s'", dimension)
+		}
+		columns[i] = index
+	}
+	value, ok := indices[utils.NameKey("Value")]
+	if !ok {
+		return nil, 0, utils.UsageError("no column maps to the 'Value'")
+	}
+	return columns, value, nil
+}
+
+// cellRejection returns the row reporting a rejected row of a CSV file
+func cellRejection(line int, record []string, reason string) any {
+	return map[string]any{"Line": line, "Row": strings.Join(record, ","), "Reason": reason}
+}
+
+var cellSetCmd = &cobra.Command{
+	Use:   "set <cube> <elements> <value>",
+	Short: "Writes a value to the cell identified by a comma separated list of elements, one for every dimension",
+	Long: `Writes a value to the cell identified by a comma separated list of elements, one for every dimension of the
+cube in order. Elements holding a comma can be quoted, as in a CSV file. The value is written as a string if the
+element of the last dimension is a string element and as a number otherwise.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		coordinates, err := csv.NewReader(strings.NewReader(args[1])).Read()
+		if err != nil {
+			return utils.UsageError("invalid list of elements '%s': %v", args[1], err)
+		}
+		cube, err := fetchCellCube(args[0])
+		if err != nil {
+			return err
+		}
+		update, err := cube.update(coordinates, args[2])
+		if err != nil {
+			return err
+		}
+		if err := utils.UpdateSandboxCells(host, instance, database, user, password, cube.name, cellSandbox, []utils.CellUpdate{update}); err != nil {
+			return err
+		}
+		fmt.Printf("The value has been written to cube '%s'.\n", cube.name)
+		return nil
+	},
+}
+
+var cellLoadCmd = &cobra.Command{
+	Use:   "load <cube>",
+	Short: "Writes the values in a CSV file to the cells of a cube",
+	Long: `Writes the values in a CSV file to the cells of a cube. The CSV file requires a header row, the columns mapping
+to the dimension with the same name, and a 'Value' column holding the values, unless mapped otherwise using --map.
+The values are written in batches. Rows referring to elements that do not exist or to consolidations, or holding
+an invalid value, are rejected and reported, as are the rows of a batch that failed to be written, without
+aborting the load.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cellBatchSize < 1 {
+			return utils.UsageError("invalid batch size %d", cellBatchSize)
+		}
+		var in io.Reader = os.Stdin
+		if cellFile != "-" {
+			file, err := os.Open(cellFile)
+			if err != nil {
+				return utils.UsageError("unable to open file: %v", err)
+			}
+			defer file.Close()
+			in = file
+		}
+		cube, err := fetchCellCube(args[0])
+		if err != nil {
+			return err
+		}
+
+		reader := csv.NewReader(in)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return utils.UsageError("unable to read the header of the file: %v", err)
+		}
+		columns, valueColumn, err := cube.cellColumns(header, cellMap)
+		if err != nil {
+			return err
+		}
+
+		var rejected []any
+		var batch []utils.CellUpdate
+		var batchLines []int
+		var batchRecords [][]string
+		written := 0
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := utils.UpdateSandboxCells(host, instance, database, user, password, cube.name, cellSandbox, batch); err != nil {
+				// Only reject the rows of the batch if the failure isn't one that would fail any other batch as well
+				if code := utils.ExitCode(err); code == utils.ExitAuth || code == utils.ExitConnection {
+					return err
+				}
+				for i, line := range batchLines {
+					rejected = append(rejected, cellRejection(line, batchRecords[i], err.Error()))
+				}
+			} else {
+				written += len(batch)
+			}
+			batch, batchLines, batchRecords = nil, nil, nil
+			return nil
+		}
+
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return utils.UsageError("unable to read line %d of the file: %v", line, err)
+			}
+			if len(record) != len(header) {
+				rejected = append(rejected, cellRejection(line, record, fmt.Sprintf("%d values, the header has %d columns", len(record), len(header))))
+				continue
+			}
+			coordinates := make([]string, len(columns))
+			for i, column := range columns {
+				coordinates[i] = record[column]
+			}
+			update, err := cube.update(coordinates, record[valueColumn])
+			if err != nil {
+				rejected = append(rejected, cellRejection(line, record, err.Error()))
+				continue
+			}
+			batch = append(batch, update)
+			batchLines = append(batchLines, line)
+			batchRecords = append(batchRecords, record)
+			if len(batch) == cellBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		// The rows of batches that failed are rejected after the rows that failed validation, report them in order
+		sort.SliceStable(rejected, func(i, j int) bool {
+			return rejected[i].(map[string]any)["Line"].(int) < rejected[j].(map[string]any)["Line"].(int)
+		})
+		if viper.GetString("output-format") == "table" {
+			fmt.Printf("%d values have been written to cube '%s'.\n", written, cube.name)
+			if len(rejected) == 0 {
+				return nil
+			}
+			fmt.Println()
+			fmt.Println("Rejected rows:")
+		}
+		if err := utils.OutputRows(rejected, "Line", "Row", "Reason"); err != nil {
+			return err
+		}
+		if len(rejected) > 0 {
+			return fmt.Errorf("%d rows have been rejected", len(rejected))
+		}
+		return nil
+	},
+}
+
+// addSandboxFlag adds the flag specifying the sandbox to write to, instead of the base data, to the command
+func addSandboxFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cellSandbox, "sandbox", "", "The sandbox to write the values to, by default the values are written to the base data")
+}
+
+func init() {
+
+	addDatabaseFlags(cellSetCmd)
+	addSandboxFlag(cellSetCmd)
+	cellCmd.AddCommand(cellSetCmd)
+
+	addDatabaseFlags(cellLoadCmd)
+	addSandboxFlag(cellLoadCmd)
+	cellLoadCmd.Flags().StringVar(&cellFile, "file", "", "The CSV file holding the values, '-' to read the values from stdin")
+	cellLoadCmd.MarkFlagRequired("file")
+	cellLoadCmd.Flags().StringArrayVar(&cellMap, "map", nil, "Map a column to a dimension, or the 'Value', as 'column=dimension', can be repeated")
+	cellLoadCmd.Flags().IntVar(&cellBatchSize, "batch-size", 1000, "The number of values written per request")
+	cellCmd.AddCommand(cellLoadCmd)
+
+	rootCmd.AddCommand(cellCmd)
+}
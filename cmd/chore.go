@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	choreStartTime     string
+	choreFrequency     string
+	choreExecutionMode string
+)
+
+// The properties retrieved for, and shown by, the chore list and show commands
+const choreQuery = "$select=Name,Active,StartTime,DSTSensitive,Frequency,ExecutionMode"
+
+var choreColumns = []string{"Name", "Active", "StartTime", "Frequency", "ExecutionMode", "NextRunTime"}
+
+// The execution modes of a chore, by the value of the --execution-mode flag
+var choreExecutionModes = map[string]string{
+	"single":   "SingleCommit",
+	"multiple": "MultipleCommit",
+}
+
+// The ISO 8601 duration, as used for the frequency of a chore, and the shorthand notation, like 1d12h, accepted by
+// the set-schedule command
+var (
+	isoDurationRegexp   = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	shortDurationRegexp = regexp.MustCompile(`^(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?$`)
+)
+
+// choreCmd represents the chore command
+var choreCmd = &cobra.Command{
+	Use:   "chore",
+	Short: "Manage and execute the chores of your TM1 database",
+}
+
+// parseDuration parses the days, hours, minutes and seconds, as matched by either of the duration expressions
+func parseDuration(re *regexp.Regexp, s string) (time.Duration, bool) {
+	match := re.FindStringSubmatch(s)
+	if match == nil || s == "" || s == "P" {
+		return 0, false
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+1] != "" {
+			n, _ := strconv.Atoi(match[i+1])
+			d += time.Duration(n) * unit
+		}
+	}
+	return d, true
+}
+
+// formatFrequency returns the frequency in the ISO 8601 notation used by the service, like P01DT12H00M00S
+func formatFrequency(d time.Duration) string {
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	return fmt.Sprintf("P%02dDT%02dH%02dM%02dS", days, d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+}
+
+// parseStartTime parses the start time of a chore, as returned by the service, which may omit the seconds
+func parseStartTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse("2006-01-02T15:04Z07:00", s)
+	}
+	return t, err
+}
+
+// nextRunTime returns the time the chore is next scheduled to run, the zero time if it isn't scheduled to run
+func nextRunTime(chore map[string]any, now time.Time) time.Time {
+	if active, _ := chore["Active"].(bool); !active {
+		return time.Time{}
+	}
+	start, err := parseStartTime(utils.Stringify(chore["StartTime"]))
+	if err != nil {
+		return time.Time{}
+	}
+	if !start.Before(now) {
+		return start
+	}
+	frequency, ok := parseDuration(isoDurationRegexp, utils.Stringify(chore["Frequency"]))
+	if !ok || frequency == 0 {
+		return time.Time{}
+	}
+	periods := (now.Sub(start) + frequency - 1) / frequency
+	return start.Add(periods * frequency)
+}
+
+// summarizeChore adds the time the chore is next scheduled to run to the chore
+func summarizeChore(chore map[string]any, now time.Time) {
+	chore["NextRunTime"] = nil
+	if next := nextRunTime(chore, now); !next.IsZero() {
+		chore["NextRunTime"] = next.Local().Format(time.RFC3339)
+	}
+}
+
+// choreTasks returns the tasks of the chore as rows holding the step, the process and its parameters
+func choreTasks(chore map[string]any) []any {
+	tasks, _ := chore["Tasks"].([]any)
+	rows := make([]any, 0, len(tasks))
+	for _, raw := range tasks {
+		task, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		var process any
+		if p, ok := task["Process"].(map[string]any); ok {
+			process = p["Name"]
+		}
+		list, _ := task["Parameters"].([]any)
+		parameters := make([]string, 0, len(list))
+		for _, raw := range list {
+			if parameter, ok := raw.(map[string]any); ok {
+				parameters = append(parameters, utils.Stringify(parameter["Name"])+"="+utils.Stringify(parameter["Value"]))
+			}
+		}
+		rows = append(rows, map[string]any{"Step": task["Step"], "Process": process, "Parameters": strings.Join(parameters, ", ")})
+	}
+	return rows
+}
+
+// choreAction invokes the action on the chore and reports its completion
+func choreAction(name, action, done string) error {
+	_, err := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, "Chores"+utils.ODataKey(name), action), map[string]any{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Chore '%s' has been %s.\n", name, done)
+	return nil
+}
+
+var choreListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of chores in the TM1 database, with their schedule and next run time",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Chores?"+choreQuery)
+			if err != nil {
+				return nil, err
+			}
+			now := time.Now()
+			chores, _ := data["value"].([]any)
+			for _, raw := range chores {
+				if chore, ok := raw.(map[string]any); ok {
+					summarizeChore(chore, now)
+				}
+			}
+			return data, nil
+		}, choreColumns...)
+	},
+}
+
+var choreShowCmd = &cobra.Command{
+	Use:   "show <chore>",
+	Short: "Show the schedule of a chore and its tasks, being the processes it executes with their parameters",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		chore, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Chores"+utils.ODataKey(args[0])+"?"+choreQuery+
+			"&$expand=Tasks($select=Step,Parameters;$expand=Process($select=Name))")
+		if err != nil {
+			return err
+		}
+		summarizeChore(chore, time.Now())
+		if viper.GetString("output-format") != "table" {
+			return utils.OutputEntity(chore)
+		}
+		tasks := choreTasks(chore)
+		delete(chore, "Tasks")
+		if err := utils.OutputEntity(chore, append(choreColumns, "DSTSensitive")...); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("Tasks:")
+		return utils.OutputRows(tasks, "Step", "Process", "Parameters")
+	},
+}
+
+var choreExecuteCmd = &cobra.Command{
+	Use:   "execute <chore>",
+	Short: "Executes a chore, regardless of its schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return choreAction(args[0], "Execute", "executed")
+	},
+}
+
+var choreActivateCmd = &cobra.Command{
+	Use:   "activate <chore>",
+	Short: "Activates a chore, having it run on its schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return choreAction(args[0], "Activate", "activated")
+	},
+}
+
+var choreDeactivateCmd = &cobra.Command{
+	Use:   "deactivate <chore>",
+	Short: "Deactivates a chore, it no longer runs on its schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return choreAction(args[0], "Deactivate", "deactivated")
+	},
+}
+
+var choreSetScheduleCmd = &cobra.Command{
+	Use:   "set-schedule <chore>",
+	Short: "Changes the start time, frequency and/or execution mode of a chore",
+	Long: `Changes the start time, frequency and/or execution mode of a chore. The start time is specified as
+'2006-01-02 15:04', in local time, or in RFC 3339 format, the frequency either like 1d, 12h or 1d6h30m or as an ISO 8601
+duration like P01DT06H30M00S, and the execution mode as either 'single' or 'multiple' commit. An active chore is
+deactivated while its schedule is changed, and activated again afterwards.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changes := make(map[string]any)
+		if choreStartTime != "" {
+			start, err := time.Parse(time.RFC3339, choreStartTime)
+			if err != nil {
+				start, err = time.ParseInLocation("2006-01-02 15:04", choreStartTime, time.Local)
+			}
+			if err != nil {
+				return utils.UsageError("invalid start time '%s', expected '2006-01-02 15:04' or RFC 3339 format", choreStartTime)
+			}
+			changes["StartTime"] = start.Format(time.RFC3339)
+		}
+		if choreFrequency != "" {
+			frequency, ok := parseDuration(shortDurationRegexp, choreFrequency)
+			if !ok {
+				frequency, ok = parseDuration(isoDurationRegexp, choreFrequency)
+			}
+			if !ok {
+				return utils.UsageError("invalid frequency '%s', expected for instance 1d, 12h, 1d6h30m or P01DT06H30M00S", choreFrequency)
+			}
+			changes["Frequency"] = formatFrequency(frequency)
+		}
+		if choreExecutionMode != "" {
+			mode, ok := choreExecutionModes[strings.ToLower(choreExecutionMode)]
+			if !ok {
+				return utils.UsageError("invalid execution mode '%s', expected 'single' or 'multiple'", choreExecutionMode)
+			}
+			changes["ExecutionMode"] = mode
+		}
+		if len(changes) == 0 {
+			return utils.UsageError("nothing to change, specify --start-time, --frequency and/or --execution-mode")
+		}
+
+		name := args[0]
+		path := "Chores" + utils.ODataKey(name)
+		chore, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"?$select=Name,Active")
+		if err != nil {
+			return err
+		}
+		// The schedule of an active chore can't be changed, deactivate it for the duration of the change
+		active, _ := chore["Active"].(bool)
+		if active {
+			if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, path, "Deactivate"), map[string]any{}); err != nil {
+				return err
+			}
+		}
+		_, err = utils.DatabaseAPIPatch(host, instance, database, user, password, path, changes)
+		if active {
+			if _, activateErr := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, path, "Activate"), map[string]any{}); activateErr != nil && err == nil {
+				return fmt.Errorf("the schedule of chore '%s' has been changed but the chore could not be activated again due to: %w", name, activateErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The schedule of chore '%s' has been changed.\n", name)
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(choreListCmd)
+	choreCmd.AddCommand(choreListCmd)
+
+	addDatabaseFlags(choreShowCmd)
+	choreCmd.AddCommand(choreShowCmd)
+
+	addDatabaseFlags(choreExecuteCmd)
+	choreCmd.AddCommand(choreExecuteCmd)
+
+	addDatabaseFlags(choreActivateCmd)
+	choreCmd.AddCommand(choreActivateCmd)
+
+	addDatabaseFlags(choreDeactivateCmd)
+	choreCmd.AddCommand(choreDeactivateCmd)
+
+	addDatabaseFlags(choreSetScheduleCmd)
+	choreSetScheduleCmd.Flags().StringVar(&choreStartTime, "start-time", "", "The time the chore starts, as '2006-01-02 15:04' in local time or in RFC 3339 format")
+	choreSetScheduleCmd.Flags().StringVar(&choreFrequency, "frequency", "", "The frequency the chore runs at, like 1d, 12h or 1d6h30m")
+	choreSetScheduleCmd.Flags().StringVar(&choreExecutionMode, "execution-mode", "", "The execution mode of the chore, either 'single' or 'multiple' commit")
+	choreCmd.AddCommand(choreSetScheduleCmd)
+
+	rootCmd.AddCommand(choreCmd)
+}
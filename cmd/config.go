@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
 	"github.com/spf13/cobra"
@@ -23,8 +25,10 @@ var listConfigKeys = map[string]bool{
 }
 
 var allowedOutputFormats = map[string]bool{
-	"json":  true,
-	"table": true,
+	"csv":    true,
+	"json":   true,
+	"ndjson": true,
+	"table":  true,
 }
 
 // configCmd represents the config command
@@ -47,36 +51,46 @@ func getConfigValue(key string) any {
 		return instance
 
 	case "database":
-		instance := getConfigValue("instance")
-		if instance == nil || instance == "" {
+		host := getConfigValue("host")
+		if host == nil || host == "" {
 			return nil
 		}
-
-		// TODO: Retrieve active database from the instance once we maintain instances and have the ability to set such active database
-		return nil
+		database, err := utils.GetDatabaseName(host.(string), "", "")
+		if err != nil || database == "" {
+			return nil
+		}
+		return database
 	}
 	return viper.Get(key)
 }
 
+// sortedKeys returns the keys of the set in alphabetical order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 var configListCmd = &cobra.Command{
 	Use:   "list [key]",
 	Short: "List all configuration values",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 1 && args[0] != "" {
 			key := args[0]
 
 			if !listConfigKeys[key] {
-				fmt.Printf("Error: '%s' is not a recognized configuration key\n", key)
-				return
+				return utils.UsageError("'%s' is not a recognized configuration key", key)
 			}
 
 			val := getConfigValue(key)
-			if val != nil && val != "" {
-				fmt.Printf("%s = %s\n", key, utils.Stringify(val))
-			} else {
-				fmt.Printf("No value set for key '%s'", key)
+			if val == nil || val == "" {
+				return utils.NotFoundError("no value set for key '%s'", key)
 			}
+			fmt.Printf("%s = %s\n", key, utils.Stringify(val))
 
 		} else {
 			for key := range listConfigKeys {
@@ -86,6 +100,7 @@ var configListCmd = &cobra.Command{
 				}
 			}
 		}
+		return nil
 	},
 }
 
@@ -93,32 +108,24 @@ var configSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set and save a configuration value",
 	Args:  cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		key := args[0]
 		value := args[1]
 
 		if !allowedConfigKeys[key] {
-			fmt.Printf("Error: '%s' is not a recognized configuration key.\n", key)
-			fmt.Println("Allowed keys are:")
-			for key := range allowedConfigKeys {
-				fmt.Println(" -", key)
-			}
-			return
+			return utils.UsageError("'%s' is not a recognized configuration key, allowed keys are: %s", key, strings.Join(sortedKeys(allowedConfigKeys), ", "))
 		}
 
 		if key == "output-format" && !allowedOutputFormats[value] {
-			fmt.Printf("Error: '%s' is not a recognized output format.\n", value)
-			fmt.Println("Supported output formats are:")
-			for value := range allowedOutputFormats {
-				fmt.Println(" -", value)
-			}
-			return
+			return utils.UsageError("'%s' is not a recognized output format, supported output formats are: %s", value, strings.Join(sortedKeys(allowedOutputFormats), ", "))
 		}
 
 		viper.Set(key, value)
-		err := utils.SaveConfiguration()
-		cobra.CheckErr(err)
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("%s set to %s\n", key, value)
+		return nil
 	},
 }
 
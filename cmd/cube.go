@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	cubeRulesFile            string
+	cubeForce                bool
+	cubeExportView           string
+	cubeExportPrivate        bool
+	cubeExportSlices         []string
+	cubeExportAttributes     []string
+	cubeExportFile           string
+	cubeExportFormat         string
+	cubeExportPageSize       int
+	cubeExportZeroes         bool
+	cubeExportConsolidations bool
+)
+
+// The properties retrieved for, and shown by, the cube list and show commands
+const cubeQuery = "$select=Name,LastSchemaUpdate,LastDataUpdate&$expand=Dimensions($select=Name)"
+
+var cubeColumns = []string{"Name", "Dimensions", "HasRules", "LastSchemaUpdate", "LastDataUpdate", "MemoryUsed"}
+
+// cubeCmd represents the cube command
+var cubeCmd = &cobra.Command{
+	Use:   "cube",
+	Short: "Manage the cubes of your TM1 database",
+}
+
+// cubeDimensionNames returns the names of the dimensions of the cube, in order
+func cubeDimensionNames(cube map[string]any) []string {
+	dimensions, _ := cube["Dimensions"].([]any)
+	names := make([]string, 0, len(dimensions))
+	for _, raw := range dimensions {
+		if dimension, ok := raw.(map[string]any); ok {
+			names = append(names, utils.Stringify(dimension["Name"]))
+		}
+	}
+	return names
+}
+
+// cubeMemory retrieves the total memory used per cube from the }StatsByCube control cube. The statistics are only
+// available if the performance monitor is running, if not, or if retrieving them fails, nil is returned.
+func cubeMemory(names ...string) map[string]any {
+	cubes := "TM1SUBSETALL([}PerfCubes])"
+	if len(names) > 0 {
+		members := make([]string, len(names))
+		for i, name := range names {
+			members[i] = "[}PerfCubes]." + utils.MDXName(name)
+		}
+		cubes = strings.Join(members, ",")
+	}
+	mdx := fmt.Sprintf("SELECT {[}StatsStatsByCube].[Total Memory Used]} ON 0, {%s} ON 1 FROM [}StatsByCube] WHERE ([}TimeIntervals].[LATEST])", cubes)
+	cellset, err := utils.ExecuteMDX(host, instance, database, user, password, mdx, "")
+	if err != nil {
+		return nil
+	}
+	axes := utils.CellsetAxes(cellset)
+	if len(axes) < 2 {
+		return nil
+	}
+	cells := utils.CellsetCells(cellset)
+	memory := make(map[string]any)
+	for row, tuple := range axes[1] {
+		if cell, ok := cells[row]; ok && len(tuple) > 0 {
+			memory[tuple[0]] = cell["Value"]
+		}
+	}
+	return memory
+}
+
+// cubesWithRules retrieves the names of the cubes, optionally only the one cube specified, that have rules, without
+// retrieving the rules themselves
+func cubesWithRules(name ...string) (map[string]bool, error) {
+	filter := "Rules ne null and Rules ne ''"
+	if len(name) > 0 {
+		filter = "Name eq " + utils.ODataString(name[0]) + " and " + filter
+	}
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes?$select=Name&"+utils.ODataFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	cubes, _ := data["value"].([]any)
+	names := make(map[string]bool, len(cubes))
+	for _, raw := range cubes {
+		if cube, ok := raw.(map[string]any); ok {
+			names[utils.Stringify(cube["Name"])] = true
+		}
+	}
+	return names, nil
+}
+
+// summarizeCube adds whether the cube has rules and the memory used by the cube, if known
+func summarizeCube(cube map[string]any, rules map[string]bool, memory map[string]any) {
+	name := utils.Stringify(cube["Name"])
+	cube["HasRules"] = rules[name]
+	if memory != nil {
+		cube["MemoryUsed"] = memory[name]
+	}
+}
+
+// cubePopulatedCells retrieves the number of populated, numeric and string, cells of the cube from the }StatsByCube
+// control cube. The statistics are only available if the performance monitor is running, if not, or if retrieving
+// them fails, false is returned.
+func cubePopulatedCells(name string) (float64, bool) {
+	mdx := fmt.Sprintf("SELECT {[}StatsStatsByCube].[Number of Populated Numeric Cells],[}StatsStatsByCube].[Number of Populated String Cells]} ON 0 FROM [}StatsByCube] WHERE ([}PerfCubes].%s,[}TimeIntervals].[LATEST])", utils.MDXName(name))
+	cellset, err := utils.ExecuteMDX(host, instance, database, user, password, mdx, "")
+	if err != nil {
+		return 0, false
+	}
+	var count float64
+	found := false
+	for _, cell := range utils.CellsetCells(cellset) {
+		if value, ok := cell["Value"].(float64); ok {
+			count += value
+			found = true
+		}
+	}
+	return count, found
+}
+
+// cubeHasData checks if any of the leaf cells of the cube holds a value. The statistics of the cube are checked first,
+// only if these aren't available, or report no populated cells, as they are only updated periodically, the cells
+// themselves are checked, which, retrieving the non empty cross join of the leaves, is only expensive if it has data.
+func cubeHasData(name string, dimensions []string) (bool, error) {
+	if count, ok := cubePopulatedCells(name); ok && count > 0 {
+		return true, nil
+	}
+
+	// Cross join the leaves of all dimensions, only retrieving the cardinality of the, non empty, resulting axis
+	sets := make([]string, len(dimensions))
+	for i, dimension := range dimensions {
+		sets[i] = fmt.Sprintf("{TM1FILTERBYLEVEL(TM1SUBSETALL(%s), 0)}", utils.MDXName(dimension))
+	}
+	mdx := fmt.Sprintf("SELECT NON EMPTY %s ON 0 FROM %s", strings.Join(sets, " * "), utils.MDXName(name))
+	cellset, err := utils.ExecuteMDX(host, instance, database, user, password, mdx, "$expand=Axes($select=Cardinality)")
+	if err != nil {
+		return false, err
+	}
+	axes, _ := cellset["Axes"].([]any)
+	if len(axes) == 0 {
+		return false, nil
+	}
+	axis, _ := axes[0].(map[string]any)
+	cardinality, _ := axis["Cardinality"].(float64)
+	return cardinality > 0, nil
+}
+
+// dimensionSettings parses the 'dimension=value' settings, returning the values by the name key of the dimension
+func dimensionSettings(settings []string, flag string) (map[string]string, error) {
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		dimension, value, ok := strings.Cut(setting, "=")
+		if !ok || dimension == "" || value == "" {
+			return nil, utils.UsageError("invalid %s '%s', expected 'dimension=value'", flag, setting)
+		}
+		values[utils.NameKey(dimension)] = value
+	}
+	return values, nil
+}
+
+// checkDimensionSettings makes sure all the 'dimension=value' settings refer to one of the dimensions specified
+func checkDimensionSettings(settings, dimensions []string, flag string) error {
+	known := make(map[string]bool, len(dimensions))
+	for _, dimension := range dimensions {
+		known[utils.NameKey(dimension)] = true
+	}
+	for _, setting := range settings {
+		dimension, _, _ := strings.Cut(setting, "=")
+		if !known[utils.NameKey(dimension)] {
+			return utils.UsageError("invalid %s '%s', the cells exported have no dimension '%s'", flag, setting, dimension)
+		}
+	}
+	return nil
+}
+
+// exportMDX returns the MDX query retrieving the cells of the cube to be exported, crossing the sets of elements of
+// all dimensions on the columns. A dimension's set is its slice, either an MDX set expression or a comma separated
+// list of elements, or, if not sliced, all its elements. Consolidations and empty cells are excluded unless included.
+func exportMDX(cube string, dimensions []string, slices map[string]string) (string, error) {
+	sets := make([]string, len(dimensions))
+	for i, dimension := range dimensions {
+		set := "{TM1SUBSETALL(" + utils.MDXName(dimension) + ")}"
+		if slice, ok := slices[utils.NameKey(dimension)]; ok {
+			set = slice
+			if !strings.HasPrefix(strings.TrimSpace(slice), "{") {
+				elements, err := csv.NewReader(strings.NewReader(slice)).Read()
+				if err != nil {
+					return "", utils.UsageError("invalid slice '%s' of dimension '%s': %v", slice, dimension, err)
+				}
+				members := make([]string, len(elements))
+				for j, element := range elements {
+					members[j] = utils.MDXName(dimension) + "." + utils.MDXName(dimension) + "." + utils.MDXName(strings.TrimSpace(element))
+				}
+				set = "{" + strings.Join(members, ",") + "}"
+			}
+		}
+		if !cubeExportConsolidations {
+			set = "{TM1FILTERBYLEVEL(" + set + ", 0)}"
+		}
+		sets[i] = set
+	}
+	nonEmpty := "NON EMPTY "
+	if cubeExportZeroes {
+		nonEmpty = ""
+	}
+	return fmt.Sprintf("SELECT %s%s ON 0 FROM %s", nonEmpty, strings.Join(sets, " * "), utils.MDXName(cube)), nil
+}
+
+// elementAttributeValues retrieves the values of the attribute of all elements of the hierarchy of the dimension, by
+// element name
+func elementAttributeValues(dimension, hierarchy, attribute string) (map[string]any, error) {
+	path := hierarchyPath(dimension, hierarchy) + "/Elements?$select=Name,Attributes/" + url.PathEscape(strings.ReplaceAll(attribute, " ", ""))
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := data["value"].([]any)
+	values := make(map[string]any, len(list))
+	for _, raw := range list {
+		if element, ok := raw.(map[string]any); ok {
+			if val, ok := elementAttribute(element, attribute); ok && val != nil && utils.Stringify(val) != "" {
+				values[utils.Stringify(element["Name"])] = val
+			}
+		}
+	}
+	return values, nil
+}
+
+var cubeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of cubes in the TM1 database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes?"+cubeQuery)
+			if err != nil {
+				return nil, err
+			}
+			rules, err := cubesWithRules()
+			if err != nil {
+				return nil, err
+			}
+			memory := cubeMemory()
+			cubes, _ := data["value"].([]any)
+			for _, raw := range cubes {
+				if cube, ok := raw.(map[string]any); ok {
+					summarizeCube(cube, rules, memory)
+				}
+			}
+			return data, nil
+		}, cubeColumns...)
+	},
+}
+
+var cubeShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the details of a cube, including its dimensions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		cube, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes"+utils.ODataKey(name)+"?"+cubeQuery)
+		if err != nil {
+			return err
+		}
+		rules, err := cubesWithRules(name)
+		if err != nil {
+			return err
+		}
+		summarizeCube(cube, rules, cubeMemory(name))
+		dimensions := cubeDimensionNames(cube)
+
+		if viper.GetString("output-format") != "table" {
+			cube["Dimensions"] = dimensions
+			return utils.OutputEntity(cube)
+		}
+
+		if err := utils.OutputEntity(cube, "Name", "HasRules", "LastSchemaUpdate", "LastDataUpdate", "MemoryUsed"); err != nil {
+			return err
+		}
+		rows := make([]any, len(dimensions))
+		for i, dimension := range dimensions {
+			rows[i] = map[string]any{"Position": i + 1, "Dimension": dimension}
+		}
+		fmt.Println()
+		fmt.Println("Dimensions:")
+		return utils.OutputRows(rows, "Position", "Dimension")
+	},
+}
+
+var cubeCreateCmd = &cobra.Command{
+	Use:   "create <name> <dimension>...",
+	Short: "Creates a new cube with the, ordered, list of dimensions specified",
+	Args:  cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimensions := make([]any, 0, len(args)-1)
+		for _, dimension := range args[1:] {
+			dimensions = append(dimensions, "Dimensions"+utils.ODataKey(dimension))
+		}
+		payload := map[string]any{"Name": args[0], "Dimensions@odata.bind": dimensions}
+		if cubeRulesFile != "" {
+			rules, err := os.ReadFile(cubeRulesFile)
+			if err != nil {
+				return utils.UsageError("unable to read rules file: %v", err)
+			}
+			payload["Rules"] = string(rules)
+		}
+		data, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Cubes", payload)
+		if err != nil {
+			return err
+		}
+		delete(data, "Rules")
+		return utils.OutputEntity(data)
+	},
+}
+
+var cubeDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes the cube, asking for confirmation if the cube holds data",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		path := "Cubes" + utils.ODataKey(name)
+
+		if !cubeForce {
+			cube, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"?$select=Name&$expand=Dimensions($select=Name)")
+			if err != nil {
+				return err
+			}
+			hasData, err := cubeHasData(name, cubeDimensionNames(cube))
+			if err != nil {
+				return err
+			}
+			if hasData {
+				confirmed, err := utils.Confirm(fmt.Sprintf("Cube '%s' holds data", name))
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					fmt.Printf("Cube '%s' has not been deleted.\n", name)
+					return nil
+				}
+			}
+		}
+
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, path); err != nil {
+			return err
+		}
+		fmt.Printf("Cube '%s' has been deleted!\n", name)
+		return nil
+	},
+}
+
+var cubeRenameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Short: "Renames the cube",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := utils.DatabaseAPIPatch(host, instance, database, user, password, "Cubes"+utils.ODataKey(args[0]), map[string]any{"Name": args[1]})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cube '%s' has been renamed to '%s'.\n", args[0], args[1])
+		return nil
+	},
+}
+
+var cubeExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Exports the cells of a cube, or of a view, to a CSV, NDJSON or Parquet file",
+	Long: `Exports the cells of a cube, or using --view of a view, to a CSV, NDJSON or Parquet file, every cell being a
+record with a column per dimension and a Value column, a column being named 'Dimension:Hierarchy' if its
+hierarchy's name is used by another hierarchy or is Value. The cells of the cube can be sliced per dimension using
+--slice, specifying a comma separated list of elements or an MDX set expression. Zeroes and consolidations are
+skipped by default. The cells are retrieved in pages, of --page-size cells, to avoid retrieving huge cellsets at
+once. Use --attribute to write the value of an attribute, if any, instead of the element name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		format, err := utils.RecordFormat(cubeExportFormat, cubeExportFile)
+		if err != nil {
+			return err
+		}
+		if cubeExportPageSize < 1 {
+			return utils.UsageError("invalid page size %d", cubeExportPageSize)
+		}
+		slices, err := dimensionSettings(cubeExportSlices, "slice")
+		if err != nil {
+			return err
+		}
+		attributes, err := dimensionSettings(cubeExportAttributes, "attribute")
+		if err != nil {
+			return err
+		}
+
+		// Create the cellset, retrieving its cells in pages afterwards
+		const expand = "$expand=Axes($select=Ordinal,Cardinality;$expand=Hierarchies($select=Name;$expand=Dimension($select=Name)))"
+		var cellset map[string]any
+		if cubeExportView != "" {
+			if len(slices) > 0 {
+				return utils.UsageError("--slice can not be combined with --view")
+			}
+			cellset, err = utils.CreateViewCellset(host, instance, database, user, password, viewsPath(name, cubeExportPrivate)+utils.ODataKey(cubeExportView), expand)
+		} else {
+			var cube map[string]any
+			if cube, err = utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes"+utils.ODataKey(name)+"?$select=Name&$expand=Dimensions($select=Name)"); err != nil {
+				return err
+			}
+			dimensions := cubeDimensionNames(cube)
+			if err := checkDimensionSettings(cubeExportSlices, dimensions, "slice"); err != nil {
+				return err
+			}
+			var mdx string
+			if mdx, err = exportMDX(name, dimensions, slices); err != nil {
+				return err
+			}
+			cellset, err = utils.CreateMDXCellset(host, instance, database, user, password, mdx, expand)
+		}
+		if err != nil {
+			return err
+		}
+		defer utils.DeleteCellset(host, instance, database, user, password, cellset)
+
+		var dimensions, hierarchies, names []string
+		for _, axis := range utils.CellsetDimensions(cellset) {
+			dimensions = append(dimensions, axis...)
+		}
+		for _, axis := range utils.CellsetHierarchies(cellset) {
+			hierarchies = append(hierarchies, axis...)
+		}
+		for _, axis := range utils.CellsetColumns(cellset) {
+			names = append(names, axis...)
+		}
+		if err := checkDimensionSettings(cubeExportAttributes, dimensions, "attribute"); err != nil {
+			return err
+		}
+		columns := make([]utils.RecordColumn, 0, len(hierarchies)+1)
+		attributeValues := make([]map[string]any, len(hierarchies))
+		for i, hierarchy := range hierarchies {
+			if attribute, ok := attributes[utils.NameKey(dimensions[i])]; ok {
+				if attributeValues[i], err = elementAttributeValues(dimensions[i], hierarchy, attribute); err != nil {
+					return err
+				}
+			}
+			columns = append(columns, utils.RecordColumn{Name: names[i]})
+		}
+		columns = append(columns, utils.RecordColumn{Name: "Value", Numeric: format == utils.RecordFormatParquet})
+
+		out := os.Stdout
+		if cubeExportFile != "" && cubeExportFile != "-" {
+			if out, err = os.Create(cubeExportFile); err != nil {
+				return utils.UsageError("unable to create file: %v", err)
+			}
+			defer out.Close()
+		}
+		writer, err := utils.NewRecordWriter(out, format, columns)
+		if err != nil {
+			return err
+		}
+
+		exported, skippedStrings := 0, 0
+		total := utils.CellsetCardinality(cellset)
+		for skip := 0; skip < total; skip += cubeExportPageSize {
+			cells, err := utils.CellsetCellsPage(host, instance, database, user, password, cellset, skip, cubeExportPageSize)
+			if err != nil {
+				return err
+			}
+		cells:
+			for _, cell := range cells {
+				if !cubeExportZeroes && utils.IsZeroCell(cell) {
+					continue
+				}
+				// Cells lacking members, for any of the hierarchies, have empty member columns
+				members, _ := cell["Members"].([]any)
+				record := make([]any, len(columns))
+				for i := 0; i < len(columns)-1; i++ {
+					record[i] = ""
+				}
+				for i := 0; i < len(members) && i < len(columns)-1; i++ {
+					member, _ := members[i].(map[string]any)
+					if !cubeExportConsolidations && member["Type"] == "Consolidated" {
+						continue cells
+					}
+					element := utils.Stringify(member["Name"])
+					record[i] = element
+					if val, ok := attributeValues[i][element]; ok {
+						record[i] = utils.Stringify(val)
+					}
+				}
+				value := cell["Value"]
+				if format == utils.RecordFormatParquet {
+					if value == nil {
+						value = 0.0
+					}
+					if _, ok := value.(float64); !ok {
+						skippedStrings++
+						continue
+					}
+				}
+				record[len(record)-1] = value
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+				exported++
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+
+		if skippedStrings > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %d string cells have been skipped, the Value column of a Parquet file being numeric\n", skippedStrings)
+		}
+		if out != os.Stdout {
+			fmt.Printf("%d cells have been exported to '%s'.\n", exported, cubeExportFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(cubeListCmd)
+	cubeCmd.AddCommand(cubeListCmd)
+
+	addDatabaseFlags(cubeShowCmd)
+	cubeCmd.AddCommand(cubeShowCmd)
+
+	addDatabaseFlags(cubeCreateCmd)
+	cubeCreateCmd.Flags().StringVar(&cubeRulesFile, "rules-file", "", "A file containing the rules for the new cube")
+	cubeCmd.AddCommand(cubeCreateCmd)
+
+	addDatabaseFlags(cubeDeleteCmd)
+	cubeDeleteCmd.Flags().BoolVar(&cubeForce, "force", false, "Delete the cube without checking if it holds data")
+	cubeCmd.AddCommand(cubeDeleteCmd)
+
+	addDatabaseFlags(cubeRenameCmd)
+	cubeCmd.AddCommand(cubeRenameCmd)
+
+	addDatabaseFlags(cubeExportCmd)
+	cubeExportCmd.Flags().StringVar(&cubeExportView, "view", "", "Export the cells of this view instead of the cells of the cube")
+	cubeExportCmd.Flags().BoolVar(&cubeExportPrivate, "private", false, "Use your private view instead of the public view")
+	cubeExportCmd.Flags().StringArrayVar(&cubeExportSlices, "slice", nil, "Slice a dimension as 'dimension=elements', a comma separated list of elements or an MDX set expression, can be repeated")
+	cubeExportCmd.Flags().StringArrayVar(&cubeExportAttributes, "attribute", nil, "Write the value of an attribute, instead of the element name, as 'dimension=attribute', can be repeated")
+	cubeExportCmd.Flags().StringVar(&cubeExportFile, "file", "", "The file to write the cells to, by default the cells are written to stdout")
+	cubeExportCmd.Flags().StringVar(&cubeExportFormat, "format", "", "The file format: csv, ndjson or parquet, by default based on the file extension, csv otherwise")
+	cubeExportCmd.Flags().IntVar(&cubeExportPageSize, "page-size", 10000, "The number of cells retrieved per request")
+	cubeExportCmd.Flags().BoolVar(&cubeExportZeroes, "include-zeroes", false, "Include the cells without a value, or with a zero value")
+	cubeExportCmd.Flags().BoolVar(&cubeExportConsolidations, "include-consolidations", false, "Include the cells of consolidated elements")
+	cubeCmd.AddCommand(cubeExportCmd)
+
+	rootCmd.AddCommand(cubeCmd)
+}
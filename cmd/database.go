@@ -8,6 +8,7 @@ import (
 
 	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // databaseCmd represents the database command
@@ -20,18 +21,17 @@ var databaseListCmd = &cobra.Command{
 	Use:   "list [name]",
 	Short: "Get the list of TM1 databases",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		var path string
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 1 && args[0] != "" {
-			path = fmt.Sprintf("Databases('%s')", args[0])
-		} else {
-			path = "Databases"
+			path := fmt.Sprintf("Databases('%s')", args[0])
+			return utils.OutputEntityFrom(func() (map[string]any, error) {
+				return utils.InstanceAPIGet(host, instance, user, password, path)
+			})
 		}
-		data, err := utils.InstanceAPIGet(host, instance, user, password, path)
-		cobra.CheckErr(err)
 		// TODO: Highlight/mark the one that is active!
-		err = utils.OutputCollection(data)
-		cobra.CheckErr(err)
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			return utils.InstanceAPIGet(host, instance, user, password, "Databases")
+		})
 	},
 }
 
@@ -39,12 +39,13 @@ var databaseCreateCmd = &cobra.Command{
 	Use:   "create <name>",
 	Short: "Creates a new TM1 database with the specified name",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		payload := map[string]any{"Name": args[0]}
 		data, err := utils.InstanceAPIPost(host, instance, user, password, "Databases", payload)
-		cobra.CheckErr(err)
-		err = utils.OutputEntity(data)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
+		return utils.OutputEntity(data)
 	},
 }
 
@@ -52,12 +53,65 @@ var databaseDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Deletes the TM1 database specified with all its artifacts",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		databaseName := args[0]
 		path := fmt.Sprintf("Databases('%s')", databaseName)
-		err := utils.InstanceAPIDelete(host, instance, user, password, path)
-		cobra.CheckErr(err)
+		if err := utils.InstanceAPIDelete(host, instance, user, password, path); err != nil {
+			return err
+		}
 		fmt.Printf("Database '%s' has been deleted!\n", databaseName)
+		return nil
+	},
+}
+
+var databaseUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Switch to using the specified database on the active instance, or unset if no name given",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the host name
+		host, err := utils.GetHostName(host)
+		if err != nil {
+			return err
+		}
+
+		// Lookup the host in list of configured hosts
+		hosts := viper.GetStringMap("hosts")
+		raw := hosts[host]
+		if raw == nil {
+			return utils.ConfigError("no configuration specified for host '%s'", host)
+		}
+		hostMap, ok := raw.(map[string]any)
+		if !ok {
+			return utils.ConfigError("invalid configuration for host '%s', format invalid", host)
+		}
+
+		// The active database applies to the active instance only
+		instance, err := utils.GetInstanceNameFromHostConfig("", hostMap)
+		if err != nil {
+			return err
+		}
+
+		// Update the host's configuration accordingly
+		if len(args) == 1 && args[0] != "" {
+			name := args[0]
+			hostMap["database"] = name
+			hosts[host] = hostMap
+			viper.Set("hosts", hosts)
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
+			fmt.Printf("Set active database on instance '%s' to '%s'.\n", instance, name)
+		} else {
+			delete(hostMap, "database")
+			hosts[host] = hostMap
+			viper.Set("hosts", hosts)
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
+			fmt.Printf("Reset active database on instance '%s'.\n", instance)
+		}
+		return nil
 	},
 }
 
@@ -81,5 +135,8 @@ func init() {
 	databaseDeleteCmd.Flags().StringVar(&password, "password", "", "The password needed to authenticate with the TM1 instance")
 	databaseCmd.AddCommand(databaseDeleteCmd)
 
+	databaseUseCmd.Flags().StringVar(&host, "host", "", "The host on which the instance is running, if not specified the active host will be used")
+	databaseCmd.AddCommand(databaseUseCmd)
+
 	rootCmd.AddCommand(databaseCmd)
 }
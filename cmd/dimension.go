@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// The properties retrieved for, and shown by, the dimension list and show commands
+const dimensionQuery = "$select=Name&$expand=Hierarchies(" + hierarchyQuery + ")"
+
+var dimensionColumns = []string{"Name", "Elements", "Levels", "DefaultMember", "AlternateHierarchies"}
+
+// dimensionCmd represents the dimension command
+var dimensionCmd = &cobra.Command{
+	Use:   "dimension",
+	Short: "Manage the dimensions of your TM1 database",
+}
+
+// summarizeDimension adds the number of elements, number of levels and default member of the default hierarchy, as
+// well as the names of the alternate hierarchies, to the dimension and returns its, summarized, hierarchies
+func summarizeDimension(dimension map[string]any) []any {
+	name := utils.Stringify(dimension["Name"])
+	hierarchies, _ := dimension["Hierarchies"].([]any)
+	alternates := []any{}
+	for _, raw := range hierarchies {
+		hierarchy, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		summarizeHierarchy(hierarchy)
+		hierarchyName := utils.Stringify(hierarchy["Name"])
+		switch {
+		case strings.EqualFold(hierarchyName, name):
+			dimension["Elements"] = hierarchy["Elements"]
+			dimension["Levels"] = hierarchy["Levels"]
+			dimension["DefaultMember"] = hierarchy["DefaultMember"]
+		case !isLeavesHierarchy(hierarchyName):
+			alternates = append(alternates, hierarchyName)
+		}
+	}
+	dimension["AlternateHierarchies"] = alternates
+	delete(dimension, "Hierarchies")
+	return hierarchies
+}
+
+var dimensionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of dimensions in the TM1 database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Dimensions?"+dimensionQuery)
+			if err != nil {
+				return nil, err
+			}
+			dimensions, _ := data["value"].([]any)
+			for _, raw := range dimensions {
+				if dimension, ok := raw.(map[string]any); ok {
+					summarizeDimension(dimension)
+				}
+			}
+			return data, nil
+		}, dimensionColumns...)
+	},
+}
+
+var dimensionShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the details of a dimension, including its hierarchies",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Dimensions"+utils.ODataKey(args[0])+"?"+dimensionQuery)
+		if err != nil {
+			return err
+		}
+		hierarchies := summarizeDimension(dimension)
+
+		if viper.GetString("output-format") != "table" {
+			dimension["Hierarchies"] = hierarchies
+			return utils.OutputEntity(dimension)
+		}
+
+		if err := utils.OutputEntity(dimension, dimensionColumns...); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("Hierarchies:")
+		return utils.OutputRows(hierarchies, hierarchyColumns...)
+	},
+}
+
+var dimensionCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates a new dimension, with its default hierarchy, with the specified name",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payload := map[string]any{"Name": args[0], "Hierarchies": []any{map[string]any{"Name": args[0]}}}
+		data, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Dimensions", payload)
+		if err != nil {
+			return err
+		}
+		return utils.OutputEntity(data)
+	},
+}
+
+var dimensionDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes the dimension, including all its hierarchies",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, "Dimensions"+utils.ODataKey(name)); err != nil {
+			return err
+		}
+		fmt.Printf("Dimension '%s' has been deleted!\n", name)
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(dimensionListCmd)
+	dimensionCmd.AddCommand(dimensionListCmd)
+
+	addDatabaseFlags(dimensionShowCmd)
+	dimensionCmd.AddCommand(dimensionShowCmd)
+
+	addDatabaseFlags(dimensionCreateCmd)
+	dimensionCmd.AddCommand(dimensionCreateCmd)
+
+	addDatabaseFlags(dimensionDeleteCmd)
+	dimensionCmd.AddCommand(dimensionDeleteCmd)
+
+	rootCmd.AddCommand(dimensionCmd)
+}
@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	elementType       string
+	elementParent     string
+	elementWeight     float64
+	elementFrom       string
+	elementTree       bool
+	elementRoot       string
+	elementDepth      int
+	elementLevel      int
+	elementAttributes []string
+)
+
+var elementColumns = []string{"Name", "Type", "Level", "Parents"}
+
+// elementCmd represents the element command
+var elementCmd = &cobra.Command{
+	Use:   "element",
+	Short: "Manage the elements, and consolidations, of a hierarchy",
+}
+
+// elementPath returns the path to the element in the hierarchy of the dimension
+func elementPath(dimension, hierarchy, element string) string {
+	return hierarchyPath(dimension, hierarchy) + "/Elements" + utils.ODataKey(element)
+}
+
+// edgePath returns the path to the edge between the parent and the component in the hierarchy of the dimension
+func edgePath(dimension, hierarchy, parent, component string) string {
+	return fmt.Sprintf("%s/Edges(ParentName=%s,ComponentName=%s)", hierarchyPath(dimension, hierarchy), utils.ODataKeyValue(parent), utils.ODataKeyValue(component))
+}
+
+// elementAttribute returns the value of the attribute of the element, noting that the service exposes attributes
+// using their name with any spaces removed
+func elementAttribute(element map[string]any, name string) (any, bool) {
+	attributes, _ := element["Attributes"].(map[string]any)
+	if val, ok := attributes[name]; ok {
+		return val, true
+	}
+	val, ok := attributes[strings.ReplaceAll(name, " ", "")]
+	return val, ok
+}
+
+// elementFilter returns a function reporting if an element matches the --level and --attribute filters, if any
+func elementFilter(cmd *cobra.Command) (func(map[string]any) bool, error) {
+	levelFilter := cmd.Flags().Changed("level")
+	attributeFilters := make(map[string]string, len(elementAttributes))
+	for _, filter := range elementAttributes {
+		name, value, ok := strings.Cut(filter, "=")
+		if !ok || name == "" {
+			return nil, utils.UsageError("invalid attribute filter '%s', expected <attribute>=<value>", filter)
+		}
+		attributeFilters[name] = value
+	}
+	return func(element map[string]any) bool {
+		if levelFilter {
+			if level, _ := element["Level"].(float64); int(level) != elementLevel {
+				return false
+			}
+		}
+		for name, value := range attributeFilters {
+			if val, ok := elementAttribute(element, name); !ok || utils.Stringify(val) != value {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// elementSelect returns the select clause for the elements, only including the attributes if they are filtered on
+func elementSelect() string {
+	if len(elementAttributes) > 0 {
+		return "$select=Name,Type,Level,Attributes"
+	}
+	return "$select=Name,Type,Level"
+}
+
+// elementLabel returns the label for the element in the tree, noting the type and weight if not the default
+func elementLabel(element map[string]any, weight any) string {
+	label := utils.Stringify(element["Name"])
+	if utils.Stringify(element["Type"]) == "String" {
+		label += " [String]"
+	}
+	if w, ok := weight.(float64); ok && w != 1 {
+		label += " (weight " + utils.Stringify(w) + ")"
+	}
+	return label
+}
+
+// elementTrees builds the trees of the elements of the hierarchy, filtered, starting at the root and limited in
+// depth as specified by the flags
+func elementTrees(dimension string, matches func(map[string]any) bool) ([]*utils.TreeNode, error) {
+	path := hierarchyPath(dimension, hierarchyName)
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"/Elements?"+elementSelect())
+	if err != nil {
+		return nil, err
+	}
+	edgeData, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"/Edges?$select=ParentName,ComponentName,Weight")
+	if err != nil {
+		return nil, err
+	}
+
+	elements, _ := data["value"].([]any)
+	byName := make(map[string]map[string]any, len(elements))
+	for _, raw := range elements {
+		if element, ok := raw.(map[string]any); ok {
+			byName[utils.NameKey(utils.Stringify(element["Name"]))] = element
+		}
+	}
+	type edge struct {
+		component string
+		weight    any
+	}
+	children := make(map[string][]edge)
+	hasParent := make(map[string]bool)
+	edges, _ := edgeData["value"].([]any)
+	for _, raw := range edges {
+		if e, ok := raw.(map[string]any); ok {
+			parent := utils.NameKey(utils.Stringify(e["ParentName"]))
+			component := utils.NameKey(utils.Stringify(e["ComponentName"]))
+			children[parent] = append(children[parent], edge{component, e["Weight"]})
+			hasParent[component] = true
+		}
+	}
+
+	// Build the tree, keeping only the nodes matching the filter, or having descendants that do, up to the depth
+	var build func(name string, weight any, depth int) *utils.TreeNode
+	build = func(name string, weight any, depth int) *utils.TreeNode {
+		element := byName[name]
+		if element == nil {
+			return nil
+		}
+		node := &utils.TreeNode{Label: elementLabel(element, weight), Data: map[string]any{"Name": element["Name"], "Type": element["Type"], "Level": element["Level"]}}
+		if weight != nil {
+			node.Data["Weight"] = weight
+		}
+		if len(elementAttributes) > 0 {
+			node.Data["Attributes"] = element["Attributes"]
+		}
+		for _, e := range children[name] {
+			if child := build(e.component, e.weight, depth+1); child != nil {
+				node.Children = append(node.Children, child)
+			}
+		}
+		if len(node.Children) == 0 && !matches(element) {
+			return nil
+		}
+		if elementDepth > 0 && depth >= elementDepth {
+			node.Children = nil
+		}
+		return node
+	}
+
+	roots := []*utils.TreeNode{}
+	if elementRoot != "" {
+		name := utils.NameKey(elementRoot)
+		if byName[name] == nil {
+			return nil, utils.NotFoundError("element '%s' not found in the hierarchy", elementRoot)
+		}
+		if root := build(name, nil, 0); root != nil {
+			roots = append(roots, root)
+		}
+		return roots, nil
+	}
+	for _, raw := range elements {
+		element, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := utils.NameKey(utils.Stringify(element["Name"]))
+		if hasParent[name] {
+			continue
+		}
+		if root := build(name, nil, 0); root != nil {
+			roots = append(roots, root)
+		}
+	}
+	return roots, nil
+}
+
+var elementListCmd = &cobra.Command{
+	Use:   "list <dimension>",
+	Short: "Get the list, or tree, of elements of a hierarchy",
+	Long: `Get the list of elements of a hierarchy, by default the same named hierarchy of the dimension, with their
+type, level and parents. Use --level and/or --attribute to only list the elements on a specific level and/or with
+specific attribute values. With --tree the elements are shown as a tree, as an ASCII tree in table format or as
+nested objects in the JSON formats, optionally starting from a specific element and/or limited in depth. When
+filtering, the tree only shows the matching elements and their ancestors.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		matches, err := elementFilter(cmd)
+		if err != nil {
+			return err
+		}
+		if elementTree {
+			roots, err := elementTrees(args[0], matches)
+			if err != nil {
+				return err
+			}
+			return utils.OutputTree(roots)
+		}
+
+		path := hierarchyPath(args[0], hierarchyName) + "/Elements?" + elementSelect() + "&$expand=Parents($select=Name)"
+		if cmd.Flags().Changed("level") {
+			path += "&" + utils.ODataFilter("Level eq "+strconv.Itoa(elementLevel))
+		}
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+			if err != nil {
+				return nil, err
+			}
+			elements, _ := data["value"].([]any)
+			filtered := make([]any, 0, len(elements))
+			for _, raw := range elements {
+				if element, ok := raw.(map[string]any); ok && matches(element) {
+					if parents, ok := element["Parents"].([]any); ok {
+						names := make([]any, 0, len(parents))
+						for _, parent := range parents {
+							if p, ok := parent.(map[string]any); ok {
+								names = append(names, p["Name"])
+							}
+						}
+						element["Parents"] = names
+					}
+					filtered = append(filtered, element)
+				}
+			}
+			data["value"] = filtered
+			return data, nil
+		}, elementColumns...)
+	},
+}
+
+var elementAddCmd = &cobra.Command{
+	Use:   "add <dimension> <element>",
+	Short: "Adds an element to a hierarchy and/or, if a parent is specified, adds it to a consolidation",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, name := args[0], args[1]
+		typeName, err := utils.ParseElementType(elementType)
+		if err != nil {
+			return err
+		}
+
+		_, err = utils.DatabaseAPIGet(host, instance, database, user, password, elementPath(dimension, hierarchyName, name)+"?$select=Name")
+		exists := err == nil
+		if err != nil && !utils.IsNotFound(err) {
+			return err
+		}
+		if exists && elementParent == "" {
+			return utils.ConflictError("element '%s' already exists in the hierarchy", name)
+		}
+
+		if !exists {
+			payload := map[string]any{"Name": name, "Type": typeName}
+			if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, hierarchyPath(dimension, hierarchyName)+"/Elements", payload); err != nil {
+				return err
+			}
+			fmt.Printf("Element '%s' has been added.\n", name)
+		}
+		if elementParent != "" {
+			payload := map[string]any{"ParentName": elementParent, "ComponentName": name, "Weight": elementWeight}
+			if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, hierarchyPath(dimension, hierarchyName)+"/Edges", payload); err != nil {
+				return err
+			}
+			fmt.Printf("Element '%s' has been added to '%s' with weight %s.\n", name, elementParent, utils.Stringify(elementWeight))
+		}
+		return nil
+	},
+}
+
+var elementRemoveCmd = &cobra.Command{
+	Use:   "remove <dimension> <element>",
+	Short: "Removes an element from a hierarchy or, if a parent is specified, from that consolidation only",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, name := args[0], args[1]
+		if elementParent != "" {
+			if err := utils.DatabaseAPIDelete(host, instance, database, user, password, edgePath(dimension, hierarchyName, elementParent, name)); err != nil {
+				return err
+			}
+			fmt.Printf("Element '%s' has been removed from '%s'.\n", name, elementParent)
+			return nil
+		}
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, elementPath(dimension, hierarchyName, name)); err != nil {
+			return err
+		}
+		fmt.Printf("Element '%s' has been deleted!\n", name)
+		return nil
+	},
+}
+
+var elementMoveCmd = &cobra.Command{
+	Use:   "move <dimension> <element> <new-parent>",
+	Short: "Moves an element to another consolidation, by default removing it from all its current parents",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, name, parent := args[0], args[1], args[2]
+		path := hierarchyPath(dimension, hierarchyName)
+
+		// All parents are retrieved, even when moving from a single parent, to find out if the element already is a
+		// component of the new parent
+		filter := "ComponentName eq " + utils.ODataString(name)
+		data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"/Edges?$select=ParentName,Weight&"+utils.ODataFilter(filter))
+		if err != nil {
+			return err
+		}
+		all, _ := data["value"].([]any)
+		var edges []any
+		existing := ""
+		for _, raw := range all {
+			from := utils.Stringify(raw.(map[string]any)["ParentName"])
+			if utils.NameKey(from) == utils.NameKey(parent) {
+				existing = from
+			}
+			if elementFrom == "" || utils.NameKey(from) == utils.NameKey(elementFrom) {
+				edges = append(edges, raw)
+			}
+		}
+		if elementFrom != "" && len(edges) == 0 {
+			return utils.NotFoundError("element '%s' is not a component of '%s'", name, elementFrom)
+		}
+
+		// Unless specified, the element keeps the weight it had in its, first, current parent
+		weight := any(1.0)
+		if cmd.Flags().Changed("weight") {
+			weight = elementWeight
+		} else if len(edges) > 0 {
+			weight = edges[0].(map[string]any)["Weight"]
+		}
+
+		// Add the element to its new parent before removing it from its current parents, if it already is a
+		// component of the new parent only its weight is updated
+		if existing != "" {
+			if _, err := utils.DatabaseAPIPatch(host, instance, database, user, password, edgePath(dimension, hierarchyName, existing, name), map[string]any{"Weight": weight}); err != nil {
+				return err
+			}
+		} else {
+			payload := map[string]any{"ParentName": parent, "ComponentName": name, "Weight": weight}
+			if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, path+"/Edges", payload); err != nil {
+				return err
+			}
+		}
+		for _, raw := range edges {
+			from := utils.Stringify(raw.(map[string]any)["ParentName"])
+			if utils.NameKey(from) == utils.NameKey(parent) {
+				continue
+			}
+			if err := utils.DatabaseAPIDelete(host, instance, database, user, password, edgePath(dimension, hierarchyName, from, name)); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Element '%s' has been moved to '%s'.\n", name, parent)
+		return nil
+	},
+}
+
+var elementRenameCmd = &cobra.Command{
+	Use:   "rename <dimension> <element> <new-name>",
+	Short: "Renames an element",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := utils.DatabaseAPIPatch(host, instance, database, user, password, elementPath(args[0], hierarchyName, args[1]), map[string]any{"Name": args[2]})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Element '%s' has been renamed to '%s'.\n", args[1], args[2])
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(elementListCmd)
+	addHierarchyFlag(elementListCmd)
+	elementListCmd.Flags().BoolVar(&elementTree, "tree", false, "Show the elements as a tree")
+	elementListCmd.Flags().StringVar(&elementRoot, "root", "", "The element to start the tree from, by default all top level elements are shown")
+	elementListCmd.Flags().IntVar(&elementDepth, "depth", 0, "The maximum depth of the tree, 0 for no limit")
+	elementListCmd.Flags().IntVar(&elementLevel, "level", 0, "Only list the elements on this level, 0 being the leaves")
+	elementListCmd.Flags().StringArrayVar(&elementAttributes, "attribute", nil, "Only list the elements with the specified attribute value, as <attribute>=<value>, can be repeated")
+	elementCmd.AddCommand(elementListCmd)
+
+	addDatabaseFlags(elementAddCmd)
+	addHierarchyFlag(elementAddCmd)
+	elementAddCmd.Flags().StringVar(&elementType, "type", "Numeric", "The type of the element: Numeric, String or Consolidated")
+	elementAddCmd.Flags().StringVar(&elementParent, "parent", "", "The consolidation to add the element to")
+	elementAddCmd.Flags().Float64Var(&elementWeight, "weight", 1, "The weight of the element in the consolidation")
+	elementCmd.AddCommand(elementAddCmd)
+
+	addDatabaseFlags(elementRemoveCmd)
+	addHierarchyFlag(elementRemoveCmd)
+	elementRemoveCmd.Flags().StringVar(&elementParent, "parent", "", "Only remove the element from this consolidation")
+	elementCmd.AddCommand(elementRemoveCmd)
+
+	addDatabaseFlags(elementMoveCmd)
+	addHierarchyFlag(elementMoveCmd)
+	elementMoveCmd.Flags().StringVar(&elementFrom, "from", "", "Only remove the element from this consolidation")
+	elementMoveCmd.Flags().Float64Var(&elementWeight, "weight", 1, "The weight of the element in the new consolidation, by default the current weight")
+	elementCmd.AddCommand(elementMoveCmd)
+
+	addDatabaseFlags(elementRenameCmd)
+	addHierarchyFlag(elementRenameCmd)
+	elementCmd.AddCommand(elementRenameCmd)
+
+	rootCmd.AddCommand(elementCmd)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	explainManage  bool
+	explainRefresh bool
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <EntityType>",
+	Short: "Show the properties and actions available on an entity type",
+	Long: `Show the properties, navigation properties and bound actions and functions of an entity, or complex, type
+as described by the service's $metadata document. The type can be specified by its (qualified) name or by the
+name of the entity set exposing it. By default the metadata of the instance API is used, specify --database to
+use the metadata of a database or --manage for the metadata of the management API.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var md *utils.Metadata
+		var err error
+		switch {
+		case explainManage:
+			md, err = utils.ManageAPIMetadata(host, explainRefresh)
+		case database != "":
+			md, err = utils.DatabaseAPIMetadata(host, instance, database, user, password, explainRefresh)
+		default:
+			md, err = utils.InstanceAPIMetadata(host, instance, user, password, explainRefresh)
+		}
+		if err != nil {
+			return err
+		}
+
+		structure := md.StructureType(args[0])
+		if structure == nil {
+			return utils.NotFoundError("type '%s' is not defined in the service's metadata", args[0])
+		}
+
+		keys := make(map[string]bool)
+		for _, key := range md.Keys(structure) {
+			keys[key] = true
+		}
+
+		properties := []any{}
+		for _, prop := range md.Properties(structure) {
+			kind := "Property"
+			if keys[prop.Name] {
+				kind = "Key"
+			}
+			properties = append(properties, map[string]any{"Name": prop.Name, "Type": prop.Type, "Kind": kind, "Nullable": prop.Nullable != "false"})
+		}
+		for _, nav := range md.NavigationProperties(structure) {
+			properties = append(properties, map[string]any{"Name": nav.Name, "Type": nav.Type, "Kind": "Navigation", "Nullable": nav.Nullable != "false"})
+		}
+
+		operations := []any{}
+		for _, op := range md.BoundOperations(structure) {
+			params := make([]string, 0, len(op.Parameters))
+			for _, param := range op.Parameters[1:] {
+				params = append(params, fmt.Sprintf("%s %s", param.Name, param.Type))
+			}
+			returns := ""
+			if op.ReturnType != nil {
+				returns = op.ReturnType.Type
+			}
+			_, collection := utils.ElementType(op.Parameters[0].Type)
+			operations = append(operations, map[string]any{"Name": op.Name, "Kind": op.Kind, "Parameters": strings.Join(params, ", "), "ReturnType": returns, "BoundToCollection": collection})
+		}
+
+		if viper.GetString("output-format") != "table" {
+			return utils.Output(map[string]any{
+				"Name":       structure.QualifiedName(),
+				"BaseType":   structure.BaseType,
+				"Properties": properties,
+				"Operations": operations,
+			})
+		}
+
+		fmt.Printf("Type: %s\n", structure.QualifiedName())
+		if structure.BaseType != "" {
+			fmt.Printf("Base type: %s\n", structure.BaseType)
+		}
+		fmt.Println()
+		fmt.Println("Properties:")
+		if err := utils.OutputRows(properties, "Name", "Type", "Kind", "Nullable"); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("Actions and functions:")
+		return utils.OutputRows(operations, "Name", "Kind", "Parameters", "ReturnType", "BoundToCollection")
+	},
+}
+
+func init() {
+
+	explainCmd.Flags().StringVar(&host, "host", "", "The host on which the instance is running, if not specified the active host will be used")
+	explainCmd.Flags().StringVar(&instance, "instance", "", "The instance to be used, if not specified the active instance will be used")
+	explainCmd.Flags().StringVar(&database, "database", "", "The database whose metadata is to be used")
+	explainCmd.Flags().StringVar(&user, "user", "", "The user name needed to authenticate with the TM1 instance")
+	explainCmd.Flags().StringVar(&password, "password", "", "The password needed to authenticate with the TM1 instance")
+	explainCmd.Flags().BoolVar(&explainManage, "manage", false, "Use the metadata of the management API of the host")
+	explainCmd.Flags().BoolVar(&explainRefresh, "refresh", false, "Ignore any cached copy of the metadata and retrieve it from the service")
+	rootCmd.AddCommand(explainCmd)
+}
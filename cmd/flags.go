@@ -0,0 +1,25 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// The hierarchy, of the dimension, a command operates on
+var hierarchyName string
+
+// addInstanceFlags adds the flags identifying the host and instance, and the user to authenticate with, to a command
+func addInstanceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&host, "host", "", "The host on which the instance is running, if not specified the active host will be used")
+	cmd.Flags().StringVar(&instance, "instance", "", "The instance to be used, if not specified the active instance will be used")
+	cmd.Flags().StringVar(&user, "user", "", "The user name needed to authenticate with the TM1 instance")
+	cmd.Flags().StringVar(&password, "password", "", "The password needed to authenticate with the TM1 instance")
+}
+
+// addDatabaseFlags adds the flags identifying the host, instance and database, and the user to authenticate with, to a command
+func addDatabaseFlags(cmd *cobra.Command) {
+	addInstanceFlags(cmd)
+	cmd.Flags().StringVar(&database, "database", "", "The database to be used, if not specified the active database will be used")
+}
+
+// addHierarchyFlag adds the flag identifying the hierarchy of the dimension to a command
+func addHierarchyFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&hierarchyName, "hierarchy", "", "The hierarchy to be used, if not specified the default, same named, hierarchy of the dimension will be used")
+}
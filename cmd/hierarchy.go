@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	hierarchyFile        string
+	hierarchyFormat      string
+	hierarchyIncremental bool
+	hierarchyDryRun      bool
+	hierarchyForce       bool
+)
+
+// The properties retrieved for, and shown by, the hierarchy list and show commands
+const hierarchyQuery = "$select=Name,Cardinality&$expand=Levels($select=Number,Name),DefaultMember($select=Name)"
+
+var hierarchyColumns = []string{"Name", "Elements", "Levels", "DefaultMember"}
+
+// hierarchyCmd represents the hierarchy command
+var hierarchyCmd = &cobra.Command{
+	Use:   "hierarchy",
+	Short: "Manage the hierarchies of the dimensions in your TM1 database",
+}
+
+// hierarchyPath returns the path to the hierarchy of the dimension, the default, same named, hierarchy if no
+// hierarchy is specified
+func hierarchyPath(dimension, hierarchy string) string {
+	if hierarchy == "" {
+		hierarchy = dimension
+	}
+	return "Dimensions" + utils.ODataKey(dimension) + "/Hierarchies" + utils.ODataKey(hierarchy)
+}
+
+// isLeavesHierarchy returns true if the hierarchy is the, system maintained, hierarchy holding all leaves
+func isLeavesHierarchy(name string) bool {
+	return strings.EqualFold(name, "Leaves")
+}
+
+// summarizeHierarchy replaces the cardinality, levels and default member of the hierarchy by the number of
+// elements, number of levels and name of the default member respectively
+func summarizeHierarchy(hierarchy map[string]any) {
+	hierarchy["Elements"] = hierarchy["Cardinality"]
+	delete(hierarchy, "Cardinality")
+	if levels, ok := hierarchy["Levels"].([]any); ok {
+		hierarchy["Levels"] = len(levels)
+	}
+	if member, ok := hierarchy["DefaultMember"].(map[string]any); ok {
+		hierarchy["DefaultMember"] = member["Name"]
+	}
+}
+
+// hierarchyArg returns the name of the hierarchy, the optional argument following the dimension, defaulting to
+// the default, same named, hierarchy of the dimension
+func hierarchyArg(args []string) string {
+	if len(args) > 1 && args[1] != "" {
+		return args[1]
+	}
+	return args[0]
+}
+
+// fetchHierarchy retrieves the elements, with their attribute values, and the edges of the hierarchy as well as
+// the types of the attributes by their name
+func fetchHierarchy(dimension, hierarchy string) (*utils.Hierarchy, map[string]string, error) {
+	path := hierarchyPath(dimension, hierarchy)
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"/ElementAttributes?$select=Name,Type")
+	if err != nil {
+		return nil, nil, err
+	}
+	definitions, _ := data["value"].([]any)
+	attributes := make([]string, 0, len(definitions))
+	attributeTypes := make(map[string]string, len(definitions))
+	for _, raw := range definitions {
+		if definition, ok := raw.(map[string]any); ok {
+			name := utils.Stringify(definition["Name"])
+			attributes = append(attributes, name)
+			attributeTypes[utils.NameKey(name)] = utils.Stringify(definition["Type"])
+		}
+	}
+
+	query := "$select=Name,Type"
+	if len(attributes) > 0 {
+		query += ",Attributes"
+	}
+	data, err = utils.DatabaseAPIGet(host, instance, database, user, password, path+"/Elements?"+query)
+	if err != nil {
+		return nil, nil, err
+	}
+	edgeData, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"/Edges?$select=ParentName,ComponentName,Weight")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := utils.NewHierarchy(attributes)
+	elements, _ := data["value"].([]any)
+	for _, raw := range elements {
+		if element, ok := raw.(map[string]any); ok {
+			e := h.AddElement(utils.Stringify(element["Name"]), utils.Stringify(element["Type"]))
+			for _, name := range attributes {
+				if val, ok := elementAttribute(element, name); ok {
+					e.Attributes[name] = val
+				}
+			}
+		}
+	}
+	edges, _ := edgeData["value"].([]any)
+	for _, raw := range edges {
+		if edge, ok := raw.(map[string]any); ok {
+			weight, _ := edge["Weight"].(float64)
+			h.AddEdge(utils.Stringify(edge["ParentName"]), utils.Stringify(edge["ComponentName"]), weight)
+		}
+	}
+	return h, attributeTypes, nil
+}
+
+// applyHierarchyChange applies a structural change to the hierarchy
+func applyHierarchyChange(dimension, hierarchy string, change utils.HierarchyChange) error {
+	path := hierarchyPath(dimension, hierarchy)
+	var err error
+	switch change.Action {
+	case utils.HierarchyAddElement:
+		_, err = utils.DatabaseAPIPost(host, instance, database, user, password, path+"/Elements", map[string]any{"Name": change.Element, "Type": change.Type})
+	case utils.HierarchyChangeType:
+		_, err = utils.DatabaseAPIPatch(host, instance, database, user, password, elementPath(dimension, hierarchy, change.Element), map[string]any{"Type": change.Type})
+	case utils.HierarchyAddComponent, utils.HierarchyMoveComponent:
+		_, err = utils.DatabaseAPIPost(host, instance, database, user, password, path+"/Edges", map[string]any{"ParentName": change.Parent, "ComponentName": change.Element, "Weight": change.Weight})
+		if err == nil && change.Action == utils.HierarchyMoveComponent {
+			err = utils.DatabaseAPIDelete(host, instance, database, user, password, edgePath(dimension, hierarchy, change.From, change.Element))
+		}
+	case utils.HierarchyUpdateWeight:
+		_, err = utils.DatabaseAPIPatch(host, instance, database, user, password, edgePath(dimension, hierarchy, change.Parent, change.Element), map[string]any{"Weight": change.Weight})
+	case utils.HierarchyRemoveComponent:
+		err = utils.DatabaseAPIDelete(host, instance, database, user, password, edgePath(dimension, hierarchy, change.Parent, change.Element))
+	case utils.HierarchyDeleteElement:
+		err = utils.DatabaseAPIDelete(host, instance, database, user, password, elementPath(dimension, hierarchy, change.Element))
+	}
+	if err != nil {
+		return fmt.Errorf("%s '%s' failed: %w", change.Action, change.Element, err)
+	}
+	return nil
+}
+
+// countDeletedElements returns the number of elements the changes delete from the hierarchy
+func countDeletedElements(changes []utils.HierarchyChange) int {
+	deletes := 0
+	for _, change := range changes {
+		if change.Action == utils.HierarchyDeleteElement {
+			deletes++
+		}
+	}
+	return deletes
+}
+
+// replaceHierarchy replaces all elements and edges of the hierarchy with the ones of the target hierarchy
+func replaceHierarchy(dimension, hierarchy string, target *utils.Hierarchy) error {
+	elements := make([]any, len(target.Elements))
+	for i, element := range target.Elements {
+		elements[i] = map[string]any{"Name": element.Name, "Type": element.Type}
+	}
+	edges := make([]any, len(target.Edges))
+	for i, edge := range target.Edges {
+		edges[i] = map[string]any{"ParentName": edge.Parent, "ComponentName": edge.Component, "Weight": edge.Weight}
+	}
+	_, err := utils.DatabaseAPIPatch(host, instance, database, user, password, hierarchyPath(dimension, hierarchy), map[string]any{"Elements": elements, "Edges": edges})
+	return err
+}
+
+var hierarchyListCmd = &cobra.Command{
+	Use:   "list <dimension>",
+	Short: "Get the list of hierarchies of a dimension",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "Dimensions" + utils.ODataKey(args[0]) + "/Hierarchies?" + hierarchyQuery
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+			if err != nil {
+				return nil, err
+			}
+			hierarchies, _ := data["value"].([]any)
+			for _, raw := range hierarchies {
+				if hierarchy, ok := raw.(map[string]any); ok {
+					summarizeHierarchy(hierarchy)
+				}
+			}
+			return data, nil
+		}, hierarchyColumns...)
+	},
+}
+
+var hierarchyShowCmd = &cobra.Command{
+	Use:   "show <dimension> [hierarchy]",
+	Short: "Show the details of a hierarchy, including its levels, by default the same named hierarchy",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		hierarchy, err := utils.DatabaseAPIGet(host, instance, database, user, password, hierarchyPath(args[0], name)+"?"+hierarchyQuery)
+		if err != nil {
+			return err
+		}
+		levels, _ := hierarchy["Levels"].([]any)
+		summarizeHierarchy(hierarchy)
+
+		if viper.GetString("output-format") != "table" {
+			hierarchy["Levels"] = levels
+			return utils.OutputEntity(hierarchy)
+		}
+
+		if err := utils.OutputEntity(hierarchy, hierarchyColumns...); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("Levels:")
+		return utils.OutputRows(levels, "Number", "Name")
+	},
+}
+
+var hierarchyCreateCmd = &cobra.Command{
+	Use:   "create <dimension> <hierarchy>",
+	Short: "Creates a new, alternate, hierarchy in the dimension",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Dimensions"+utils.ODataKey(args[0])+"/Hierarchies", map[string]any{"Name": args[1]})
+		if err != nil {
+			return err
+		}
+		return utils.OutputEntity(data)
+	},
+}
+
+var hierarchyDeleteCmd = &cobra.Command{
+	Use:   "delete <dimension> <hierarchy>",
+	Short: "Deletes an alternate hierarchy from the dimension",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, name := args[0], args[1]
+		if strings.EqualFold(dimension, name) {
+			return utils.UsageError("the hierarchy '%s' is the default hierarchy of the dimension, delete the dimension instead", name)
+		}
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, hierarchyPath(dimension, name)); err != nil {
+			return err
+		}
+		fmt.Printf("Hierarchy '%s' has been deleted from dimension '%s'!\n", name, dimension)
+		return nil
+	},
+}
+
+var hierarchyExportCmd = &cobra.Command{
+	Use:   "export <dimension> [hierarchy]",
+	Short: "Exports the elements, their attribute values, and the structure of a hierarchy to a file",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := utils.HierarchyFormat(hierarchyFormat, hierarchyFile)
+		if err != nil {
+			return err
+		}
+		h, _, err := fetchHierarchy(args[0], hierarchyArg(args))
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if hierarchyFile != "" {
+			file, err := os.Create(hierarchyFile)
+			if err != nil {
+				return utils.UsageError("unable to create file: %v", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		if err := utils.WriteHierarchy(w, h, format); err != nil {
+			return err
+		}
+		if hierarchyFile != "" {
+			fmt.Printf("Exported %d elements of hierarchy '%s' to: %s\n", len(h.Elements), hierarchyArg(args), hierarchyFile)
+		}
+		return nil
+	},
+}
+
+var hierarchyImportCmd = &cobra.Command{
+	Use:   "import <dimension> [hierarchy] --file <file>",
+	Short: "Updates a hierarchy to match the elements, attribute values and structure in a file",
+	Long: `Updates a hierarchy, by default the same named hierarchy of the dimension, to match the elements, attribute
+values and structure in a parent-child CSV, level-column CSV or JSON file. By default the elements and edges of
+the hierarchy are replaced as a whole, with --incremental only the changes needed, elements being added, removed
+or moved, are applied one by one. Use --dry-run to show the changes without applying them. Attribute values are
+only updated for the attributes in the file, which need to exist in the dimension. Elements not in the file are
+deleted, if any are you are asked to confirm the import, use --force to import the file without confirmation.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, hierarchy := args[0], hierarchyArg(args)
+		format, err := utils.HierarchyFormat(hierarchyFormat, hierarchyFile)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(hierarchyFile)
+		if err != nil {
+			return utils.UsageError("unable to open file: %v", err)
+		}
+		defer file.Close()
+		target, err := utils.ReadHierarchy(file, format)
+		if err != nil {
+			return err
+		}
+
+		current, attributeTypes, err := fetchHierarchy(dimension, hierarchy)
+		if err != nil {
+			return err
+		}
+		if err := resolveAttributes(target, current, attributeTypes); err != nil {
+			return err
+		}
+
+		// Validate the aliases as they will be once the changes have been applied
+		final := make([]*utils.HierarchyElement, len(target.Elements))
+		for i, element := range target.Elements {
+			final[i] = &utils.HierarchyElement{Name: element.Name, Attributes: make(map[string]any)}
+			if existing := current.Element(element.Name); existing != nil {
+				for name, val := range existing.Attributes {
+					final[i].Attributes[name] = val
+				}
+			}
+			for name, val := range element.Attributes {
+				final[i].Attributes[name] = val
+			}
+		}
+		if err := validateAliases(dimension, hierarchy, final, current.Attributes, attributeTypes); err != nil {
+			return err
+		}
+		changes := utils.DiffHierarchy(current, target)
+
+		if hierarchyDryRun {
+			if len(changes) == 0 {
+				fmt.Println("No changes.")
+				return nil
+			}
+			rows := make([]any, len(changes))
+			for i, change := range changes {
+				rows[i] = change.Row()
+			}
+			return utils.OutputRows(rows, "Action", "Element", "Type", "Parent", "From", "Weight", "Attribute", "Value")
+		}
+
+		if deletes := countDeletedElements(changes); deletes > 0 && !hierarchyForce {
+			confirmed, err := utils.Confirm(fmt.Sprintf("%d elements of hierarchy '%s' are not in the file and will be deleted", deletes, hierarchy))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Printf("Hierarchy '%s' has not been updated.\n", hierarchy)
+				return nil
+			}
+		}
+
+		var updates []utils.CellUpdate
+		structural := 0
+		for _, change := range changes {
+			if change.Action == utils.HierarchySetAttribute {
+				updates = append(updates, attributeUpdate(dimension, hierarchy, change.Element, change.Attribute, change.Value))
+				continue
+			}
+			structural++
+			if hierarchyIncremental {
+				if err := applyHierarchyChange(dimension, hierarchy, change); err != nil {
+					return err
+				}
+			}
+		}
+		if !hierarchyIncremental && structural > 0 {
+			if err := replaceHierarchy(dimension, hierarchy, target); err != nil {
+				return err
+			}
+		}
+		if len(updates) > 0 {
+			if err := utils.UpdateCells(host, instance, database, user, password, attributesCube(dimension), updates); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Hierarchy '%s' has been updated: %d structural changes and %d attribute values.\n", hierarchy, structural, len(updates))
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(hierarchyListCmd)
+	hierarchyCmd.AddCommand(hierarchyListCmd)
+
+	addDatabaseFlags(hierarchyShowCmd)
+	hierarchyCmd.AddCommand(hierarchyShowCmd)
+
+	addDatabaseFlags(hierarchyCreateCmd)
+	hierarchyCmd.AddCommand(hierarchyCreateCmd)
+
+	addDatabaseFlags(hierarchyDeleteCmd)
+	hierarchyCmd.AddCommand(hierarchyDeleteCmd)
+
+	addDatabaseFlags(hierarchyExportCmd)
+	hierarchyExportCmd.Flags().StringVar(&hierarchyFile, "file", "", "The file to export the hierarchy to, if not specified the hierarchy is written to stdout")
+	hierarchyExportCmd.Flags().StringVar(&hierarchyFormat, "format", "", "The format of the file: parent-child, levels or json, by default based on the file's extension")
+	hierarchyCmd.AddCommand(hierarchyExportCmd)
+
+	addDatabaseFlags(hierarchyImportCmd)
+	hierarchyImportCmd.Flags().StringVar(&hierarchyFile, "file", "", "The file to import the hierarchy from")
+	hierarchyImportCmd.Flags().StringVar(&hierarchyFormat, "format", "", "The format of the file: parent-child, levels or json, by default based on the file's extension")
+	hierarchyImportCmd.Flags().BoolVar(&hierarchyIncremental, "incremental", false, "Only apply the changes needed, one by one, instead of replacing the hierarchy as a whole")
+	hierarchyImportCmd.Flags().BoolVar(&hierarchyDryRun, "dry-run", false, "Show the changes without applying them")
+	hierarchyImportCmd.Flags().BoolVar(&hierarchyForce, "force", false, "Delete the elements not in the file without asking for confirmation")
+	hierarchyImportCmd.MarkFlagRequired("file")
+	hierarchyCmd.AddCommand(hierarchyImportCmd)
+
+	rootCmd.AddCommand(hierarchyCmd)
+}
@@ -24,29 +24,25 @@ var hostListCmd = &cobra.Command{
 	Use:   "list [name]",
 	Short: "List all configured hosts",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		hosts := viper.GetStringMap("hosts")
 
 		if len(hosts) == 0 {
 			fmt.Println("No hosts configured.")
-			return
+			return nil
 		}
 
 		if len(args) == 1 && args[0] != "" {
 			name := args[0]
 			host := hosts[name]
 			if host == nil {
-				fmt.Printf("no configuration specified for host '%s'\n", name)
-				return
+				return utils.NotFoundError("no configuration specified for host '%s'", name)
 			}
-			err := utils.OutputMap(host.(map[string]any), "Name")
-			cobra.CheckErr(err)
-			return
+			return utils.OutputMap(host.(map[string]any), "Name")
 		}
 
 		// TODO: Highlight/mark the one that's active
-		err := utils.OutputMap(hosts, "Name")
-		cobra.CheckErr(err)
+		return utils.OutputMap(hosts, "Name")
 	},
 }
 
@@ -54,7 +50,7 @@ var hostSetCmd = &cobra.Command{
 	Use:   "set <name>",
 	Short: "Set one or more configuration values for the specified host",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
 		// Validate or initialize the hosts entry
@@ -67,8 +63,7 @@ var hostSetCmd = &cobra.Command{
 		} else {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid host format for '%s'\n", name)
-				return
+				return utils.ConfigError("invalid host format for '%s'", name)
 			}
 			hostMap = cast
 		}
@@ -106,14 +101,16 @@ var hostSetCmd = &cobra.Command{
 		}
 
 		if !changed {
-			fmt.Println("No values provided to set. Use --service_root_url, --root_client_id or --root_client_secret.")
-			return
+			return utils.UsageError("no values provided to set, use --service_root_url, --root_client_id or --root_client_secret")
 		}
 
 		hosts[name] = hostMap
 		viper.Set("hosts", hosts)
-		cobra.CheckErr(utils.SaveConfiguration())
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("Updated host '%s'\n", name)
+		return nil
 	},
 }
 
@@ -121,23 +118,27 @@ var hostUseCmd = &cobra.Command{
 	Use:   "use [name]",
 	Short: "Switch to using the specified host, or unset if no name given",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 1 && args[0] != "" {
 			name := args[0]
 
 			hosts := viper.GetStringMap("hosts")
 			if _, exists := hosts[name]; !exists {
-				fmt.Printf("Host '%s' is not defined. Please configure a host before making it the active host.\n", name)
-				return
+				return utils.NotFoundError("host '%s' is not defined, please configure a host before making it the active host", name)
 			}
 			viper.Set("host", name)
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Printf("Set active host to '%s'.\n", name)
 		} else {
 			viper.Set("host", "")
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Println("Reset active host.")
 		}
+		return nil
 	},
 }
 
@@ -145,13 +146,12 @@ var hostDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Delete host from the list of, configured, hosts",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
 		hosts := viper.GetStringMap("hosts")
 		if _, ok := hosts[name]; !ok {
-			fmt.Printf("Host '%s' does not exist.\n", name)
-			return
+			return utils.NotFoundError("host '%s' does not exist", name)
 		}
 
 		// Unset host if we're deleting the active one
@@ -164,8 +164,11 @@ var hostDeleteCmd = &cobra.Command{
 		// Delete the host from the list of hosts
 		delete(hosts, name)
 		viper.Set("Hosts", hosts)
-		cobra.CheckErr(utils.SaveConfiguration())
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("Deleted host '%s'.\n", name)
+		return nil
 	},
 }
 
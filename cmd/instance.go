@@ -27,18 +27,17 @@ var instanceListCmd = &cobra.Command{
 	Use:   "list [name]",
 	Short: "Get the list of TM1 service instances",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		var path string
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 1 && args[0] != "" {
-			path = fmt.Sprintf("Instances('%s')", args[0])
-		} else {
-			path = "Instances"
+			path := fmt.Sprintf("Instances('%s')", args[0])
+			return utils.OutputEntityFrom(func() (map[string]any, error) {
+				return utils.ManageAPIGet(host, path)
+			})
 		}
-		data, err := utils.ManageAPIGet(host, path)
-		cobra.CheckErr(err)
 		// TODO: Highlight/mark the one that is active adding it if no configuration for that instance exists!
-		err = utils.OutputCollection(data)
-		cobra.CheckErr(err)
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			return utils.ManageAPIGet(host, "Instances")
+		})
 	},
 }
 
@@ -46,12 +45,13 @@ var instanceCreateCmd = &cobra.Command{
 	Use:   "create <name>",
 	Short: "Creates a new TM1 service instance with the name specified",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		payload := map[string]any{"Name": args[0]}
 		data, err := utils.ManageAPIPost(host, "Instances", payload)
-		cobra.CheckErr(err)
-		err = utils.OutputEntity(data)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
+		return utils.OutputEntity(data)
 	},
 }
 
@@ -59,12 +59,14 @@ var instanceDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Deletes the TM1 service instance and all its associated databases and artifacts",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		instanceName := args[0]
 		path := fmt.Sprintf("Instances('%s')", instanceName)
-		err := utils.ManageAPIDelete(host, path)
-		cobra.CheckErr(err)
+		if err := utils.ManageAPIDelete(host, path); err != nil {
+			return err
+		}
 		fmt.Printf("Instance '%s' has been deleted!\n", instanceName)
+		return nil
 	},
 }
 
@@ -72,39 +74,49 @@ var instanceUseCmd = &cobra.Command{
 	Use:   "use [name]",
 	Short: "Switch to using the specified instance, or unset if no name given",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get the host name
 		host, err := utils.GetHostName(host)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
 
 		// Lookup the host in list of configured hosts
 		hosts := viper.GetStringMap("hosts")
 		raw := hosts[host]
 		if raw == nil {
-			fmt.Printf("no configuration specified for host '%s'", host)
-			return
+			return utils.ConfigError("no configuration specified for host '%s'", host)
 		}
 		hostMap, ok := raw.(map[string]any)
 		if !ok {
-			fmt.Printf("invalid configuration for host '%s', format invalid", host)
-			return
+			return utils.ConfigError("invalid configuration for host '%s', format invalid", host)
 		}
 
 		// Update the host's configuration accordingly
 		if len(args) == 1 && args[0] != "" {
 			name := args[0]
+			// The active database belongs to the previously active instance
+			if hostMap["instance"] != name {
+				delete(hostMap, "database")
+			}
 			hostMap["instance"] = name
 			hosts[host] = hostMap
 			viper.Set("hosts", hosts)
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Printf("Set active instance on host '%s' to '%s'.\n", host, name)
 		} else {
 			delete(hostMap, "instance")
+			delete(hostMap, "database")
 			hosts[host] = hostMap
 			viper.Set("hosts", hosts)
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Printf("Reset active instance on host '%s'.\n", host)
 		}
+		return nil
 	},
 }
 
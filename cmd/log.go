@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logSince      string
+	logUntil      string
+	logLevel      string
+	logLogger     string
+	logContains   string
+	logTop        int
+	logFollow     bool
+	logCube       string
+	logByUser     string
+	logElements   []string
+	logFile       string
+	logObjectType string
+	logObjectName string
+)
+
+// The properties shown by the log messages command
+var messageLogColumns = []string{"TimeStamp", "Level", "Logger", "Message"}
+
+// The properties shown by the log transactions command
+var transactionLogColumns = []string{"TimeStamp", "User", "Cube", "Elements", "OldValue", "NewValue"}
+
+// The properties shown by the log audit command
+var auditLogColumns = []string{"TimeStamp", "UserName", "ObjectType", "ObjectName", "Description"}
+
+// The terminal colors of the levels of message log entries, levels not listed are not colored
+var messageLevelColors = map[string]string{
+	"Fatal":   "\033[1;31m",
+	"Error":   "\033[31m",
+	"Warning": "\033[33m",
+	"Debug":   "\033[90m",
+}
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Query, and follow, the logs of your TM1 database",
+}
+
+// parseLogTime parses a time specified as a duration, like 30m, 2h or 1d, relative to now, as '2006-01-02 15:04' in
+// local time, or in RFC 3339 format
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	if d, ok := parseDuration(shortDurationRegexp, s); ok {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, utils.UsageError("invalid time '%s', expected a duration like 30m, 2h or 1d, '2006-01-02 15:04' or RFC 3339 format", s)
+}
+
+// logTimeFilter returns the conditions limiting the entries to those logged in the time range specified by the
+// --since and --until flags, if any, the time being held by the time property
+func logTimeFilter(timeProperty string) ([]string, error) {
+	now := time.Now()
+	var conditions []string
+	for _, bound := range []struct {
+		value, operator string
+	}{{logSince, "ge"}, {logUntil, "le"}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseLogTime(bound.value, now)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", timeProperty, bound.operator, t.UTC().Format(time.RFC3339)))
+	}
+	return conditions, nil
+}
+
+// anyOf returns the condition the property equals any of the comma separated values, normalized by normalize if
+// specified
+func anyOf(property, values string, normalize func(string) string) string {
+	var conditions []string
+	for _, value := range strings.Split(values, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			if normalize != nil {
+				value = normalize(value)
+			}
+			conditions = append(conditions, property+" eq "+utils.ODataString(value))
+		}
+	}
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "(" + strings.Join(conditions, " or ") + ")"
+}
+
+// messageLevel returns the level as named by the service, like Error or Warning
+func messageLevel(level string) string {
+	return strings.ToUpper(level[:1]) + strings.ToLower(level[1:])
+}
+
+// queryLog returns the entries of the log, matching the conditions, in chronological order. If since is specified
+// the entries logged since are returned, if top is specified the top most recent entries are, and all otherwise.
+func queryLog(log, timeProperty string, conditions []string, since string, top int) ([]any, error) {
+	if since != "" {
+		conditions = append(conditions, timeProperty+" ge "+since)
+	}
+	query := "$orderby=" + timeProperty + "%20asc"
+	if since == "" && top > 0 {
+		query = "$orderby=" + timeProperty + "%20desc&$top=" + strconv.Itoa(top)
+	}
+	if len(conditions) > 0 {
+		query += "&" + utils.ODataFilter(strings.Join(conditions, " and "))
+	}
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, log+"?"+query)
+	if err != nil {
+		return nil, err
+	}
+	entries, _ := data["value"].([]any)
+	if since == "" && top > 0 {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries, nil
+}
+
+// The number of log entries retrieved per request when exporting a log
+const logPageSize = 10000
+
+// pageLog retrieves the entries of the log matching the conditions, in chronological order, in pages of logPageSize
+// entries, passing each page to process as soon as it is retrieved
+func pageLog(log, timeProperty string, conditions []string, process func(entries []any) error) error {
+	query := "$orderby=" + timeProperty + "%20asc"
+	if len(conditions) > 0 {
+		query += "&" + utils.ODataFilter(strings.Join(conditions, " and "))
+	}
+	for skip := 0; ; skip += logPageSize {
+		data, err := utils.DatabaseAPIGet(host, instance, database, user, password, fmt.Sprintf("%s?%s&$skip=%d&$top=%d", log, query, skip, logPageSize))
+		if err != nil {
+			return err
+		}
+		entries, _ := data["value"].([]any)
+		if err := process(entries); err != nil {
+			return err
+		}
+		if len(entries) < logPageSize {
+			return nil
+		}
+	}
+}
+
+// summarizeEntries applies summarize, if specified, to the log entries
+func summarizeEntries(entries []any, summarize func(entry map[string]any)) {
+	if summarize == nil {
+		return
+	}
+	for _, raw := range entries {
+		if entry, ok := raw.(map[string]any); ok {
+			summarize(entry)
+		}
+	}
+}
+
+// outputLog outputs the entries of the log matching the conditions, summarized by summarize if specified, or, if
+// following the log, keeps outputting the entries being logged until interrupted
+func outputLog(log, timeProperty string, conditions []string, columns []string, summarize func(entry map[string]any), line func(entry map[string]any) string) error {
+	if logTop <= 0 {
+		return utils.UsageError("invalid number of entries %d, expected a positive number", logTop)
+	}
+	if logFollow {
+		if logUntil != "" {
+			return utils.UsageError("--until can't be used when following the log")
+		}
+		top := logTop
+		return utils.FollowLog(func(since string) ([]any, error) {
+			entries, err := queryLog(log, timeProperty, conditions, since, top)
+			// Once the most recent entries have been output, all entries logged since are
+			top = 0
+			summarizeEntries(entries, summarize)
+			return entries, err
+		}, timeProperty, line)
+	}
+	entries, err := queryLog(log, timeProperty, conditions, "", logTop)
+	if err != nil {
+		return err
+	}
+	summarizeEntries(entries, summarize)
+	return utils.OutputRows(entries, columns...)
+}
+
+// messageLine formats a message log entry as a line, colored by its level
+func messageLine(entry map[string]any) string {
+	level := utils.Stringify(entry["Level"])
+	line := fmt.Sprintf("%s %-7s %s %s", utils.Stringify(entry["TimeStamp"]), level, utils.Stringify(entry["Logger"]), utils.Stringify(entry["Message"]))
+	if color, ok := messageLevelColors[level]; ok {
+		return color + line + "\033[0m"
+	}
+	return line
+}
+
+var logMessagesCmd = &cobra.Command{
+	Use:   "messages",
+	Short: "Query, or follow, the message log",
+	Long: `Query, or follow, the message log, by default showing the 100 most recent entries. The entries can be filtered by
+the time they were logged, using --since and --until, specified as a duration like 30m, 2h or 1d, relative to now, as
+'2006-01-02 15:04' in local time, or in RFC 3339 format, by level, using --level, which can hold a comma separated
+list of levels, by logger, and by the text the message contains. Using --follow the log is polled for new entries,
+which are streamed, colored by level on a terminal and as NDJSON otherwise, until interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conditions, err := logTimeFilter("TimeStamp")
+		if err != nil {
+			return err
+		}
+		if logLevel != "" {
+			conditions = append(conditions, anyOf("Level", logLevel, messageLevel))
+		}
+		if logLogger != "" {
+			conditions = append(conditions, "Logger eq "+utils.ODataString(logLogger))
+		}
+		if logContains != "" {
+			conditions = append(conditions, "contains(Message,"+utils.ODataString(logContains)+")")
+		}
+		return outputLog("MessageLogEntries", "TimeStamp", conditions, messageLogColumns, nil, messageLine)
+	},
+}
+
+// summarizeTransaction adds the elements making up the tuple of the cell changed as a single value
+func summarizeTransaction(entry map[string]any) {
+	tuple, _ := entry["Tuple"].([]any)
+	elements := make([]string, len(tuple))
+	for i, element := range tuple {
+		elements[i] = utils.Stringify(element)
+	}
+	entry["Elements"] = strings.Join(elements, ", ")
+}
+
+// transactionLine formats a transaction log entry as a line
+func transactionLine(entry map[string]any) string {
+	return fmt.Sprintf("%s %s %s (%s): %s -> %s", utils.Stringify(entry["TimeStamp"]), utils.Stringify(entry["User"]), utils.Stringify(entry["Cube"]),
+		utils.Stringify(entry["Elements"]), utils.Stringify(entry["OldValue"]), utils.Stringify(entry["NewValue"]))
+}
+
+// exportTransactions writes all transaction log entries matching the conditions to a CSV file, a column per
+// dimension if the entries are those of a single cube, a single column holding the elements otherwise
+func exportTransactions(conditions []string) error {
+	var dimensions []string
+	if logCube != "" {
+		cube, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes"+utils.ODataKey(logCube)+"?$select=Name&$expand=Dimensions($select=Name)")
+		if err != nil {
+			return err
+		}
+		dimensions = cubeDimensionNames(cube)
+	}
+	columns := []utils.RecordColumn{{Name: "TimeStamp"}, {Name: "User"}, {Name: "Cube"}}
+	if dimensions == nil {
+		columns = append(columns, utils.RecordColumn{Name: "Elements"})
+	}
+	for _, dimension := range dimensions {
+		columns = append(columns, utils.RecordColumn{Name: dimension})
+	}
+	columns = append(columns, utils.RecordColumn{Name: "OldValue"}, utils.RecordColumn{Name: "NewValue"})
+
+	out := os.Stdout
+	var err error
+	if logFile != "-" {
+		if out, err = os.Create(logFile); err != nil {
+			return utils.UsageError("unable to create file: %v", err)
+		}
+		defer out.Close()
+	}
+	writer, err := utils.NewRecordWriter(out, utils.RecordFormatCSV, columns)
+	if err != nil {
+		return err
+	}
+
+	// The transactions are retrieved, and written, a page at a time as the log can be huge
+	exported := 0
+	err = pageLog("TransactionLogEntries", "TimeStamp", conditions, func(entries []any) error {
+		summarizeEntries(entries, summarizeTransaction)
+		for _, raw := range entries {
+			entry, _ := raw.(map[string]any)
+			record := []any{entry["TimeStamp"], entry["User"], entry["Cube"]}
+			if dimensions == nil {
+				record = append(record, entry["Elements"])
+			} else {
+				tuple, _ := entry["Tuple"].([]any)
+				for i := range dimensions {
+					var element any
+					if i < len(tuple) {
+						element = tuple[i]
+					}
+					record = append(record, element)
+				}
+			}
+			if err := writer.Write(append(record, entry["OldValue"], entry["NewValue"])); err != nil {
+				return err
+			}
+			exported++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if out != os.Stdout {
+		fmt.Printf("%d transactions have been exported to '%s'.\n", exported, logFile)
+	}
+	return nil
+}
+
+var logTransactionsCmd = &cobra.Command{
+	Use:   "transactions",
+	Short: "Query, follow, or export, the transaction log, showing who changed which cells",
+	Long: `Query, follow, or export, the transaction log, showing who changed which cells, with the old and new value, by
+default showing the 100 most recent entries. The entries can be filtered by the time they were logged, using --since
+and --until, like the messages command, by cube, by the user that made the change, using --by-user, and by the
+elements of the cells changed, using --element, which can be repeated, only showing changes to cells with all
+elements specified. Using --file all entries matching the filters are exported to a CSV file, having a column for
+every dimension if --cube is specified, the entries being retrieved, and written, in pages of 10000 entries.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conditions, err := logTimeFilter("TimeStamp")
+		if err != nil {
+			return err
+		}
+		if logCube != "" {
+			conditions = append(conditions, "Cube eq "+utils.ODataString(logCube))
+		}
+		if logByUser != "" {
+			conditions = append(conditions, "User eq "+utils.ODataString(logByUser))
+		}
+		for _, element := range logElements {
+			conditions = append(conditions, "Tuple/any(e: e eq "+utils.ODataString(element)+")")
+		}
+		if logFile != "" {
+			if logFollow {
+				return utils.UsageError("--file can't be used when following the log")
+			}
+			return exportTransactions(conditions)
+		}
+		return outputLog("TransactionLogEntries", "TimeStamp", conditions, transactionLogColumns, summarizeTransaction, transactionLine)
+	},
+}
+
+// auditLine formats an audit log entry as a line
+func auditLine(entry map[string]any) string {
+	return fmt.Sprintf("%s %s %s '%s': %s", utils.Stringify(entry["TimeStamp"]), utils.Stringify(entry["UserName"]), utils.Stringify(entry["ObjectType"]),
+		utils.Stringify(entry["ObjectName"]), utils.Stringify(entry["Description"]))
+}
+
+var logAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query, or follow, the audit log, showing who created, deleted or changed which objects",
+	Long: `Query, or follow, the audit log, showing who created, deleted or changed which objects, including security
+assignments, by default showing the 100 most recent entries. Audit logging has to be enabled for the database. The
+entries can be filtered by the time they were logged, using --since and --until, like the messages command, by the
+type of object, using --object-type, which can hold a comma separated list of types, by object name, and by the
+user, using --by-user. Using --follow the log is polled for new entries, which are streamed as NDJSON when not
+writing to a terminal, ready to be shipped into a SIEM.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conditions, err := logTimeFilter("TimeStamp")
+		if err != nil {
+			return err
+		}
+		if logObjectType != "" {
+			conditions = append(conditions, anyOf("ObjectType", logObjectType, nil))
+		}
+		if logObjectName != "" {
+			conditions = append(conditions, "ObjectName eq "+utils.ODataString(logObjectName))
+		}
+		if logByUser != "" {
+			conditions = append(conditions, "UserName eq "+utils.ODataString(logByUser))
+		}
+		return outputLog("AuditLogEntries", "TimeStamp", conditions, auditLogColumns, nil, auditLine)
+	},
+}
+
+// addLogFlags adds the flags limiting the entries of a log to the time range, and number of entries, specified
+// and the flag to follow the log to a command
+func addLogFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&logSince, "since", "", "Only show entries logged since, like 30m, 2h, 1d, '2006-01-02 15:04' or RFC 3339 format")
+	cmd.Flags().StringVar(&logUntil, "until", "", "Only show entries logged until, like 30m, 2h, 1d, '2006-01-02 15:04' or RFC 3339 format")
+	cmd.Flags().IntVar(&logTop, "top", 100, "The number of most recent entries to show")
+	cmd.Flags().BoolVarP(&logFollow, "follow", "f", false, "Keep polling for, and showing, new entries until interrupted")
+}
+
+func init() {
+
+	addDatabaseFlags(logMessagesCmd)
+	addLogFlags(logMessagesCmd)
+	logMessagesCmd.Flags().StringVar(&logLevel, "level", "", "Only show entries of the level, or comma separated levels, like Error or Warning")
+	logMessagesCmd.Flags().StringVar(&logLogger, "logger", "", "Only show entries logged by the logger, like TM1.Server")
+	logMessagesCmd.Flags().StringVar(&logContains, "contains", "", "Only show entries with a message containing the text")
+	logCmd.AddCommand(logMessagesCmd)
+
+	addDatabaseFlags(logTransactionsCmd)
+	addLogFlags(logTransactionsCmd)
+	logTransactionsCmd.Flags().StringVar(&logCube, "cube", "", "Only show changes to the cells of the cube")
+	logTransactionsCmd.Flags().StringVar(&logByUser, "by-user", "", "Only show changes made by the user")
+	logTransactionsCmd.Flags().StringArrayVar(&logElements, "element", nil, "Only show changes to cells with the element, can be repeated")
+	logTransactionsCmd.Flags().StringVar(&logFile, "file", "", "The CSV file to export all entries matching the filters to, '-' to write them to stdout")
+	logCmd.AddCommand(logTransactionsCmd)
+
+	addDatabaseFlags(logAuditCmd)
+	addLogFlags(logAuditCmd)
+	logAuditCmd.Flags().StringVar(&logObjectType, "object-type", "", "Only show entries for objects of the type, or comma separated types, like Cube or Process")
+	logAuditCmd.Flags().StringVar(&logObjectName, "object-name", "", "Only show entries for objects with the name")
+	logAuditCmd.Flags().StringVar(&logByUser, "by-user", "", "Only show changes made by the user")
+	logCmd.AddCommand(logAuditCmd)
+
+	rootCmd.AddCommand(logCmd)
+}
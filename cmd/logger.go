@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var loggerFor string
+
+// The levels a logger can be set to, by their name in lower case
+var loggerLevels = map[string]string{
+	"off":     "Off",
+	"fatal":   "Fatal",
+	"error":   "Error",
+	"warning": "Warning",
+	"info":    "Info",
+	"debug":   "Debug",
+}
+
+// loggerCmd represents the logger command
+var loggerCmd = &cobra.Command{
+	Use:   "logger",
+	Short: "Manage the levels of the loggers of your TM1 database",
+}
+
+// setLoggerLevel sets the level of the logger, returning the level it was set to before
+func setLoggerLevel(name, level string) (string, error) {
+	path := "Loggers" + utils.ODataKey(name)
+	logger, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"?$select=Name,Level")
+	if err != nil {
+		return "", err
+	}
+	if _, err := utils.DatabaseAPIPatch(host, instance, database, user, password, path, map[string]any{"Level": level}); err != nil {
+		return "", err
+	}
+	return utils.Stringify(logger["Level"]), nil
+}
+
+// resolveDatabase returns the names of the host, instance and database the command operates on, those specified or
+// otherwise the active ones
+func resolveDatabase() (string, string, string, error) {
+	hostName, err := utils.GetHostName(host)
+	if err != nil {
+		return "", "", "", err
+	}
+	instanceName, err := utils.GetInstanceName(hostName, instance)
+	if err != nil {
+		return "", "", "", err
+	}
+	databaseName, err := utils.GetDatabaseName(hostName, instance, database)
+	if err != nil {
+		return "", "", "", err
+	}
+	return hostName, instanceName, databaseName, nil
+}
+
+// revertCommand returns the command reverting the level of the logger to the level specified, explicitly specifying
+// the host, instance and database, as resolved by resolveDatabase, so it reverts the level of the same database
+// regardless of the ones active by the time it is used
+func revertCommand(name, level string) string {
+	args := []string{"tm1ctl", "logger", "set", name, level, "--host", host, "--instance", instance, "--database", database}
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes the argument, if needed, so the shell passes it as is: in single quotes for POSIX shells and in
+// double quotes for the Windows shells
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+=", r))
+	}) == -1 {
+		return arg
+	}
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+var loggerListCmd = &cobra.Command{
+	Use:   "list [filter]",
+	Short: "Get the list of loggers, with their level, optionally only those with a name containing the filter",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Loggers?$select=Name,Level")
+			if err != nil {
+				return nil, err
+			}
+			if len(args) > 0 {
+				filter := strings.ToLower(args[0])
+				filterCollection(data, func(logger map[string]any) bool {
+					return strings.Contains(strings.ToLower(utils.Stringify(logger["Name"])), filter)
+				})
+			}
+			return data, nil
+		}, "Name", "Level")
+	},
+}
+
+var loggerSetCmd = &cobra.Command{
+	Use:   "set <logger> <level>",
+	Short: "Sets the level of a logger, optionally for a limited duration only",
+	Long: `Sets the level of a logger to either Off, Fatal, Error, Warning, Info or Debug. Using --for, specified like 15m,
+1h or 1h30m, the previous level is restored once the duration has passed, tm1ctl waiting until then, or until
+interrupted, terminated or its terminal is closed, in which case the previous level is restored right away. The
+command to restore the previous level is printed as well, should tm1ctl not be able to restore it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		level, ok := loggerLevels[strings.ToLower(args[1])]
+		if !ok {
+			return utils.UsageError("invalid level '%s', expected Off, Fatal, Error, Warning, Info or Debug", args[1])
+		}
+		var duration time.Duration
+		if loggerFor != "" {
+			if duration, ok = parseDuration(shortDurationRegexp, loggerFor); !ok || duration <= 0 {
+				return utils.UsageError("invalid duration '%s', expected for instance 15m, 1h or 1h30m", loggerFor)
+			}
+		}
+
+		// The database is resolved, and used from here on, so the level is restored on the same database, even if
+		// the active database changes in the meantime, and the command to restore it manually can be printed
+		var err error
+		if host, instance, database, err = resolveDatabase(); err != nil {
+			return err
+		}
+		previous, err := setLoggerLevel(name, level)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The level of logger '%s' has been changed from '%s' to '%s'.\n", name, previous, level)
+		if duration == 0 {
+			return nil
+		}
+
+		fmt.Printf("The level will be restored to '%s' in %s, press Ctrl+C to restore it now. To restore it manually use:\n  %s\n",
+			previous, duration, revertCommand(name, previous))
+		ctx, stop := utils.InterruptContext()
+		defer stop()
+		select {
+		case <-ctx.Done():
+		case <-time.After(duration):
+		}
+		if _, err := setLoggerLevel(name, previous); err != nil {
+			return fmt.Errorf("the level of logger '%s' could not be restored to '%s' due to: %w", name, previous, err)
+		}
+		fmt.Printf("The level of logger '%s' has been restored to '%s'.\n", name, previous)
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(loggerListCmd)
+	loggerCmd.AddCommand(loggerListCmd)
+
+	addDatabaseFlags(loggerSetCmd)
+	loggerSetCmd.Flags().StringVar(&loggerFor, "for", "", "The duration, like 15m, 1h or 1h30m, after which the previous level is restored")
+	loggerCmd.AddCommand(loggerSetCmd)
+
+	rootCmd.AddCommand(loggerCmd)
+}
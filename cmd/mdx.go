@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	mdxFile       string
+	mdxSkipZeroes bool
+	mdxValues     string
+)
+
+// readQuery returns the query passed as argument or, if none, the content of the file, stdin if the file is '-'
+func readQuery(args []string, file string) (string, error) {
+	if len(args) == 1 {
+		if file != "" {
+			return "", utils.UsageError("specify either a query or --file, not both")
+		}
+		return args[0], nil
+	}
+	if file == "" {
+		return "", utils.UsageError("no query specified, pass the query as argument or use --file")
+	}
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", utils.UsageError("unable to read query: %v", err)
+	}
+	return string(content), nil
+}
+
+// formattedValues returns whether formatted, instead of raw, values are to be shown, by default formatted values
+// are shown in table format and raw values otherwise
+func formattedValues(values string) (bool, error) {
+	switch values {
+	case "":
+		return viper.GetString("output-format") == "table", nil
+	case "formatted":
+		return true, nil
+	case "raw":
+		return false, nil
+	}
+	return false, utils.UsageError("invalid values '%s', expected either 'formatted' or 'raw'", values)
+}
+
+// mdxCmd represents the mdx command
+var mdxCmd = &cobra.Command{
+	Use:   "mdx [query]",
+	Short: "Execute an MDX query and show the resulting cellset",
+	Long: `Execute an MDX query, passed as argument or read from --file, and show the resulting cellset. In table
+format the cellset is shown as a grid, with the tuples on the columns axis as columns and the tuples on the rows
+axis as rows, in csv and ndjson formats every cell is a record holding its members and value, and in json format
+the cellset is shown as returned by the service. The cellset is deleted from the server once retrieved.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query, err := readQuery(args, mdxFile)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(query) == "" {
+			return utils.UsageError("the query is empty")
+		}
+		formatted, err := formattedValues(mdxValues)
+		if err != nil {
+			return err
+		}
+		cellset, err := utils.ExecuteMDX(host, instance, database, user, password, query, "")
+		if err != nil {
+			return err
+		}
+		return utils.OutputCellset(cellset, mdxSkipZeroes, formatted)
+	},
+}
+
+func init() {
+	addDatabaseFlags(mdxCmd)
+	mdxCmd.Flags().StringVar(&mdxFile, "file", "", "A file containing the query, '-' to read the query from stdin")
+	mdxCmd.Flags().BoolVar(&mdxSkipZeroes, "skip-zeroes", false, "Skip the cells without a value, or with a zero value, or in table format the rows and columns holding nothing but such cells")
+	mdxCmd.Flags().StringVar(&mdxValues, "values", "", "Show either 'formatted' or 'raw' values, by default formatted values in table format and raw values otherwise")
+	rootCmd.AddCommand(mdxCmd)
+}
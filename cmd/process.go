@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	processParams         []string
+	processDir            string
+	processFormat         string
+	processIncludeControl bool
+)
+
+// The properties retrieved for, and shown by, the process list and show commands
+const processQuery = "$select=Name,HasSecurityAccess,DataSource,Parameters"
+
+var processColumns = []string{"Name", "DataSource", "Parameters", "HasSecurityAccess"}
+
+// The execute status codes of a process that completed successfully
+var processSuccessStatusCodes = map[string]bool{
+	"CompletedSuccessfully": true,
+	"CompletedWithMessages": true,
+}
+
+// processCmd represents the process command
+var processCmd = &cobra.Command{
+	Use:   "process",
+	Short: "Manage and execute the TurboIntegrator processes of your TM1 database",
+}
+
+// processParameters returns the parameters of the process
+func processParameters(process map[string]any) []map[string]any {
+	list, _ := process["Parameters"].([]any)
+	parameters := make([]map[string]any, 0, len(list))
+	for _, raw := range list {
+		if parameter, ok := raw.(map[string]any); ok {
+			parameters = append(parameters, parameter)
+		}
+	}
+	return parameters
+}
+
+// summarizeProcess replaces the data source of the process by its type and the parameters by their names
+func summarizeProcess(process map[string]any) {
+	if dataSource, ok := process["DataSource"].(map[string]any); ok {
+		process["DataSource"] = dataSource["Type"]
+	}
+	parameters := processParameters(process)
+	names := make([]any, len(parameters))
+	for i, parameter := range parameters {
+		names[i] = parameter["Name"]
+	}
+	process["Parameters"] = names
+}
+
+// executeParameters returns the parameters to execute the process with, validating the 'name=value' pairs against
+// the parameters of the process and passing the values of numeric parameters as numbers
+func executeParameters(process map[string]any, pairs []string) ([]any, error) {
+	defined := make(map[string]map[string]any)
+	for _, parameter := range processParameters(process) {
+		defined[strings.ToLower(utils.Stringify(parameter["Name"]))] = parameter
+	}
+	parameters := make([]any, 0, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, utils.UsageError("invalid parameter '%s', expected 'name=value'", pair)
+		}
+		parameter, ok := defined[strings.ToLower(name)]
+		if !ok {
+			return nil, utils.UsageError("process '%s' has no parameter '%s'", process["Name"], name)
+		}
+		var val any = value
+		if parameter["Type"] == "Numeric" {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, utils.UsageError("invalid value '%s' for numeric parameter '%s'", value, parameter["Name"])
+			}
+			val = number
+		}
+		parameters = append(parameters, map[string]any{"Name": parameter["Name"], "Value": val})
+	}
+	return parameters, nil
+}
+
+// executeProcess executes the process, or the unbound process, at the path with the payload and reports the
+// outcome. If the process didn't complete successfully, its error log, if any, is retrieved and shown as well.
+func executeProcess(name, path string, payload map[string]any) error {
+	result, err := utils.DatabaseAPIPost(host, instance, database, user, password, path+"?$expand=ErrorLogFile($select=Filename)", payload)
+	if err != nil {
+		return err
+	}
+	status := utils.Stringify(result["ProcessExecuteStatusCode"])
+	var logFile, errorLog string
+	if file, ok := result["ErrorLogFile"].(map[string]any); ok {
+		logFile = utils.Stringify(file["Filename"])
+	}
+	if !processSuccessStatusCodes[status] && logFile != "" {
+		content, err := utils.DatabaseAPIGetContent(host, instance, database, user, password, "ErrorLogFiles"+utils.ODataKey(logFile)+"/Content")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: error log '%s' could not be retrieved due to: %v\n", logFile, err)
+		}
+		errorLog = string(content)
+	}
+
+	if viper.GetString("output-format") != "table" {
+		outcome := map[string]any{"Name": name, "ProcessExecuteStatusCode": status}
+		if logFile != "" {
+			outcome["ErrorLogFile"] = logFile
+			outcome["ErrorLog"] = errorLog
+		}
+		if err := utils.OutputEntity(outcome); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Process '%s' has completed with status '%s'.\n", name, status)
+		if errorLog != "" {
+			fmt.Fprintf(os.Stderr, "\nError log '%s':\n%s\n", logFile, strings.TrimRight(errorLog, "\r\n"))
+		}
+	}
+	if !processSuccessStatusCodes[status] {
+		return utils.ProcessError("process '%s' did not complete successfully, status '%s'", name, status)
+	}
+	return nil
+}
+
+var processListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of processes in the TM1 database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Processes?"+processQuery)
+			if err != nil {
+				return nil, err
+			}
+			processes, _ := data["value"].([]any)
+			for _, raw := range processes {
+				if process, ok := raw.(map[string]any); ok {
+					summarizeProcess(process)
+				}
+			}
+			return data, nil
+		}, processColumns...)
+	},
+}
+
+var processShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the details of a process, including its parameters",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		process, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Processes"+utils.ODataKey(args[0])+"?"+processQuery)
+		if err != nil {
+			return err
+		}
+		if viper.GetString("output-format") != "table" {
+			return utils.OutputEntity(process)
+		}
+
+		parameters := processParameters(process)
+		summarizeProcess(process)
+		if err := utils.OutputEntity(process, "Name", "DataSource", "HasSecurityAccess"); err != nil {
+			return err
+		}
+		rows := make([]any, len(parameters))
+		for i, parameter := range parameters {
+			rows[i] = parameter
+		}
+		fmt.Println()
+		fmt.Println("Parameters:")
+		return utils.OutputRows(rows, "Name", "Type", "Value", "Prompt")
+	},
+}
+
+var processExecuteCmd = &cobra.Command{
+	Use:   "execute <name>",
+	Short: "Executes a process and reports its status, and error log if it failed",
+	Long: `Executes a process, passing the parameters specified using --param, and reports the status it completed
+with. If the process did not complete successfully its error log, if any, is shown and tm1ctl exits with exit code 9.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "Processes" + utils.ODataKey(args[0])
+		process, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"?$select=Name,Parameters")
+		if err != nil {
+			return err
+		}
+		parameters, err := executeParameters(process, processParams)
+		if err != nil {
+			return err
+		}
+		return executeProcess(utils.Stringify(process["Name"]), utils.DatabaseActionPath(host, instance, database, user, password, path, "ExecuteWithReturn"), map[string]any{"Parameters": parameters})
+	},
+}
+
+// processDirs returns the directories holding the process file sets to be pushed, being the directories specified
+// if holding a process file set, or otherwise their subdirectories that do
+func processDirs(dirs []string) ([]string, error) {
+	var result []string
+	for _, dir := range dirs {
+		if utils.IsProcessDir(dir) {
+			result = append(result, dir)
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, utils.UsageError("unable to read directory: %v", err)
+		}
+		for _, entry := range entries {
+			if sub := filepath.Join(dir, entry.Name()); entry.IsDir() && utils.IsProcessDir(sub) {
+				result = append(result, sub)
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, utils.UsageError("no process file sets found in: %s", strings.Join(dirs, ", "))
+	}
+	return result, nil
+}
+
+// compileProcess has the service compile the process definition, returning the errors found, each with its
+// Procedure, LineNumber and Message
+func compileProcess(process map[string]any) ([]any, error) {
+	data, err := utils.DatabaseAPIPost(host, instance, database, user, password, "CompileProcess", map[string]any{"Process": process})
+	if err != nil {
+		return nil, err
+	}
+	errors, _ := data["value"].([]any)
+	return errors, nil
+}
+
+// pushProcess creates, or updates, the process using the definition in the file set in the directory, if changed and
+// if it compiles, returning the action taken and the compile errors, if any
+func pushProcess(dir string) (string, string, []any, error) {
+	process, err := utils.ReadProcessFiles(dir)
+	if err != nil {
+		return "", "", nil, err
+	}
+	name := process["Name"].(string)
+	path := "Processes" + utils.ODataKey(name)
+	current, err := utils.DatabaseAPIGet(host, instance, database, user, password, path+"?"+utils.ProcessQuery)
+	if err != nil && !utils.IsNotFound(err) {
+		return name, "", nil, err
+	}
+	if current != nil {
+		normalized, err := utils.NormalizeProcess(current)
+		if err != nil {
+			return name, "", nil, err
+		}
+		if reflect.DeepEqual(normalized, process) {
+			return name, "Unchanged", nil, nil
+		}
+	}
+
+	compileErrors, err := compileProcess(process)
+	if err != nil {
+		return name, "", nil, err
+	}
+	if len(compileErrors) > 0 {
+		return name, "Failed", compileErrors, nil
+	}
+	if current != nil {
+		_, err = utils.DatabaseAPIPatch(host, instance, database, user, password, path, process)
+		return name, "Updated", nil, err
+	}
+	_, err = utils.DatabaseAPIPost(host, instance, database, user, password, "Processes", process)
+	return name, "Created", nil, err
+}
+
+var processPullCmd = &cobra.Command{
+	Use:   "pull [name...]",
+	Short: "Writes processes, or all processes if none specified, as file sets to a directory",
+	Long: `Writes processes, or all processes if none are specified, as file sets to a directory. Every process is
+written to a subdirectory, named after the process, holding a header, in YAML or JSON format, with the parameters,
+variables and data source of the process and a file for the code of each of its procedures: prolog.ti, metadata.ti,
+data.ti and epilog.ti. Control processes are skipped, when pulling all processes, unless --include-control is
+specified. Processes whose names map to the same directory, characters not allowed in file names being replaced by
+an underscore, aren't written, nor are processes whose directory holds another process already.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var processes []any
+		if len(args) == 0 {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Processes?"+utils.ProcessQuery)
+			if err != nil {
+				return err
+			}
+			processes, _ = data["value"].([]any)
+		} else {
+			for _, name := range args {
+				process, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Processes"+utils.ODataKey(name)+"?"+utils.ProcessQuery)
+				if err != nil {
+					return err
+				}
+				processes = append(processes, process)
+			}
+		}
+
+		// Determine the directories of all processes first, making sure no two processes share a directory
+		var pulled []map[string]any
+		var dirs []string
+		for _, raw := range processes {
+			process, _ := raw.(map[string]any)
+			name := utils.Stringify(process["Name"])
+			if len(args) == 0 && !processIncludeControl && strings.HasPrefix(name, "}") {
+				continue
+			}
+			pulled = append(pulled, process)
+			dirs = append(dirs, filepath.Join(processDir, utils.ProcessDirName(name)))
+		}
+		if err := checkProcessDirs(pulled, dirs); err != nil {
+			return err
+		}
+
+		var rows []any
+		for i, process := range pulled {
+			if err := utils.WriteProcessFiles(dirs[i], process, processFormat); err != nil {
+				return err
+			}
+			rows = append(rows, map[string]any{"Name": process["Name"], "Directory": dirs[i]})
+		}
+		return utils.OutputRows(rows, "Name", "Directory")
+	},
+}
+
+// checkProcessDirs makes sure that no two processes are written to the same directory, noting that the names of
+// different processes can map to the same directory name and that file systems can be case insensitive, and that no
+// directory holds the file set of another process already
+func checkProcessDirs(processes []map[string]any, dirs []string) error {
+	owners := make(map[string]string, len(dirs))
+	for i, dir := range dirs {
+		name := utils.Stringify(processes[i]["Name"])
+		key := strings.ToLower(dir)
+		if owner, ok := owners[key]; ok {
+			return utils.ConflictError("processes '%s' and '%s' would both be written to '%s', no processes have been written", owner, name, dir)
+		}
+		owners[key] = name
+		if utils.IsProcessDir(dir) {
+			existing, err := utils.ReadProcessFiles(dir)
+			if err == nil && utils.NameKey(utils.Stringify(existing["Name"])) != utils.NameKey(name) {
+				return utils.ConflictError("directory '%s' holds process '%s' already, process '%s' can't be written to it, no processes have been written", dir, existing["Name"], name)
+			}
+		}
+	}
+	return nil
+}
+
+var processPushCmd = &cobra.Command{
+	Use:   "push [dir...]",
+	Short: "Creates, or updates, the processes from the file sets in the directories specified",
+	Long: `Creates, or updates, the processes from the file sets in the directories specified, or the current directory
+if none are specified. A directory either holds a process file set, as written by the pull command, or holds
+subdirectories that do. Before a process is created or overwritten its definition is compiled by the service, a
+process failing to compile is reported, with the errors found, and left untouched. Processes that didn't change are
+left untouched as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		dirs, err := processDirs(args)
+		if err != nil {
+			return err
+		}
+
+		var rows, compileErrors []any
+		failed := 0
+		for _, dir := range dirs {
+			name, action, errors, err := pushProcess(dir)
+			if err != nil {
+				// Only continue with the next process if the failure isn't one that would fail any other push as well
+				if code := utils.ExitCode(err); code == utils.ExitAuth || code == utils.ExitConnection {
+					return err
+				}
+				action = "Failed"
+				errors = []any{map[string]any{"Message": err.Error()}}
+			}
+			if action == "Failed" {
+				failed++
+			}
+			for _, raw := range errors {
+				if compileError, ok := raw.(map[string]any); ok {
+					compileError["Process"] = name
+					compileErrors = append(compileErrors, compileError)
+				}
+			}
+			rows = append(rows, map[string]any{"Name": name, "Directory": dir, "Action": action, "Errors": errors})
+		}
+
+		if err := utils.OutputRows(rows, "Name", "Directory", "Action"); err != nil {
+			return err
+		}
+		if failed == 0 {
+			return nil
+		}
+		if viper.GetString("output-format") == "table" {
+			fmt.Println()
+			fmt.Println("Errors:")
+			if err := utils.OutputRows(compileErrors, "Process", "Procedure", "LineNumber", "Message"); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("%d processes failed to be pushed", failed)
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(processListCmd)
+	processCmd.AddCommand(processListCmd)
+
+	addDatabaseFlags(processShowCmd)
+	processCmd.AddCommand(processShowCmd)
+
+	addDatabaseFlags(processExecuteCmd)
+	processExecuteCmd.Flags().StringArrayVar(&processParams, "param", nil, "A parameter to pass to the process as 'name=value', can be repeated")
+	processCmd.AddCommand(processExecuteCmd)
+
+	addDatabaseFlags(processPullCmd)
+	processPullCmd.Flags().StringVar(&processDir, "dir", ".", "The directory to write the process file sets to")
+	processPullCmd.Flags().StringVar(&processFormat, "format", utils.ProcessFormatYAML, "The format of the process header: yaml or json")
+	processPullCmd.Flags().BoolVar(&processIncludeControl, "include-control", false, "Include the control processes when pulling all processes")
+	processCmd.AddCommand(processPullCmd)
+
+	addDatabaseFlags(processPushCmd)
+	processCmd.AddCommand(processPushCmd)
+
+	rootCmd.AddCommand(processCmd)
+}
@@ -1,32 +1,47 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+
+	// Set once the command's arguments and flags have been validated and the command is about to run
+	commandStarted bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "tm1ctl",
 	Short: "TM1 v12 control utility",
 	Long:  `The TM1 v12 control utility allows you to manage your TM1 v12 service from the command line.`,
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
-	// Run: func(cmd *cobra.Command, args []string) { },
+	// Errors are reported, consistently, by Execute
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		commandStarted = true
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Any error returned by a command is reported on stderr, as a JSON error object if
+// a JSON output format is selected, and determines the exit code (see README).
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		// Errors raised before the command got to run are the result of invalid commands, arguments or flags
+		if !commandStarted && utils.ExitCode(err) == utils.ExitError {
+			err = &utils.CLIError{ExitCode: utils.ExitUsage, Err: err}
+		}
+		os.Exit(utils.ReportError(err))
 	}
 }
 
@@ -38,8 +53,18 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tm1ctl.json)")
-	rootCmd.PersistentFlags().String("output", "", "set the output format for this request, either 'table' or 'json' (defaults to output_format config)")
+	rootCmd.PersistentFlags().String("output", "", "set the output format for this request, either 'table', 'json', 'ndjson' or 'csv' (defaults to output_format config)")
 	viper.BindPFlag("output-format", rootCmd.PersistentFlags().Lookup("output"))
+	rootCmd.PersistentFlags().Bool("all-columns", false, "include navigation properties and annotations when rendering tables")
+	viper.BindPFlag("all-columns", rootCmd.PersistentFlags().Lookup("all-columns"))
+	rootCmd.PersistentFlags().Int("max-items", 3, "the number of items shown for arrays when rendering tables, 0 to only show the number of items")
+	viper.BindPFlag("max-items", rootCmd.PersistentFlags().Lookup("max-items"))
+	rootCmd.PersistentFlags().Bool("wrap", false, "wrap, instead of truncate, cells that don't fit the width of the terminal")
+	viper.BindPFlag("wrap", rootCmd.PersistentFlags().Lookup("wrap"))
+	rootCmd.PersistentFlags().BoolP("watch", "w", false, "keep polling and outputting the result of list commands until interrupted")
+	viper.BindPFlag("watch", rootCmd.PersistentFlags().Lookup("watch"))
+	rootCmd.PersistentFlags().Duration("interval", 2*time.Second, "the interval at which to poll in watch mode")
+	viper.BindPFlag("interval", rootCmd.PersistentFlags().Lookup("interval"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -63,13 +88,14 @@ func initConfig() {
 		viper.SetConfigFile(cfgFile)
 		if err := viper.ReadInConfig(); err != nil {
 			// Error reading specified config file
-			fmt.Printf("Error loading config: %v\n", err)
-			os.Exit(1)
+			os.Exit(utils.ReportError(utils.ConfigError("failed to load config: %v", err)))
 		}
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+		if err != nil {
+			os.Exit(utils.ReportError(utils.ConfigError("failed to determine home directory: %v", err)))
+		}
 
 		// Search config in home directory with name ".tm1ctl" (without extension).
 		viper.AddConfigPath(home)
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rulesFile  string
+	rulesForce bool
+)
+
+// rulesCmd represents the rules command
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage the rules of a cube",
+}
+
+// getRules retrieves the rules of the cube
+func getRules(cube string) (string, error) {
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Cubes"+utils.ODataKey(cube)+"?$select=Rules")
+	if err != nil {
+		return "", err
+	}
+	rules, _ := data["Rules"].(string)
+	return rules, nil
+}
+
+// checkRules has the service check the rules of the cube, as saved, returning the errors found, each with its
+// LineNumber and Message. The service offers no way to check rules without saving them first.
+func checkRules(cube string) ([]any, error) {
+	data, err := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, "Cubes"+utils.ODataKey(cube), "CheckRules"), map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	errors, _ := data["value"].([]any)
+	return errors, nil
+}
+
+// reportRuleErrors outputs the errors found in the rules and returns an error reporting the number of errors
+func reportRuleErrors(errors []any) error {
+	if err := utils.OutputRows(errors, "LineNumber", "Message"); err != nil {
+		return err
+	}
+	return fmt.Errorf("the rules hold %d errors", len(errors))
+}
+
+// updateRules saves the rules of the cube and has the service check them, the service only being able to check saved
+// rules. The previous rules are restored unless the rules have been checked and hold no errors or, if forced, hold
+// errors, so rules that couldn't be checked never remain in effect.
+func updateRules(cube, rules string) (err error) {
+	previous, err := getRules(cube)
+	if err != nil {
+		return err
+	}
+	path := "Cubes" + utils.ODataKey(cube)
+	if _, err := utils.DatabaseAPIPatch(host, instance, database, user, password, path, map[string]any{"Rules": rules}); err != nil {
+		return err
+	}
+	keep := false
+	defer func() {
+		if keep {
+			return
+		}
+		if _, restoreErr := utils.DatabaseAPIPatch(host, instance, database, user, password, path, map[string]any{"Rules": previous}); restoreErr != nil {
+			err = fmt.Errorf("%w, and the previous rules could not be restored due to: %v", err, restoreErr)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "The rules of cube '%s' have not been changed.\n", cube)
+	}()
+
+	errors, err := checkRules(cube)
+	if err != nil {
+		return fmt.Errorf("the rules could not be checked due to: %w", err)
+	}
+	if len(errors) > 0 {
+		keep = rulesForce
+		return reportRuleErrors(errors)
+	}
+	keep = true
+	fmt.Printf("The rules of cube '%s' have been saved.\n", cube)
+	return nil
+}
+
+// editorCommand returns the command, as set in the EDITOR environment variable, to edit the file with
+func editorCommand(file string) *exec.Cmd {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], file)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd
+}
+
+var rulesGetCmd = &cobra.Command{
+	Use:   "get <cube>",
+	Short: "Writes the rules of a cube to stdout or, using --file, to a file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := getRules(args[0])
+		if err != nil {
+			return err
+		}
+		if rulesFile == "" || rulesFile == "-" {
+			fmt.Print(rules)
+			return nil
+		}
+		if err := os.WriteFile(rulesFile, []byte(rules), 0o644); err != nil {
+			return utils.UsageError("unable to write file: %v", err)
+		}
+		fmt.Printf("The rules of cube '%s' have been written to '%s'.\n", args[0], rulesFile)
+		return nil
+	},
+}
+
+var rulesSetCmd = &cobra.Command{
+	Use:   "set <cube>",
+	Short: "Sets the rules of a cube to the rules in a file",
+	Long: `Sets the rules of a cube to the rules in a file, '-' reading the rules from stdin. The service can only check
+rules once saved, the rules are therefore saved first and checked right after, meaning they are in effect for a
+brief moment, even if they hold errors. If any errors are found, the errors are reported, with their line number,
+and the previous rules are restored, unless --force is specified. If the rules can't be checked the previous rules
+are restored as well.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var rules []byte
+		var err error
+		if rulesFile == "-" {
+			rules, err = io.ReadAll(os.Stdin)
+		} else {
+			rules, err = os.ReadFile(rulesFile)
+		}
+		if err != nil {
+			return utils.UsageError("unable to read rules: %v", err)
+		}
+		return updateRules(args[0], string(rules))
+	},
+}
+
+var rulesEditCmd = &cobra.Command{
+	Use:   "edit <cube>",
+	Short: "Edits the rules of a cube using the editor set in the EDITOR environment variable",
+	Long: `Edits the rules of a cube using the editor set in the EDITOR environment variable, vi (or notepad on
+Windows) if not set. The rules are saved once the editor is closed, if changed, and checked once saved, meaning
+they are in effect for a brief moment, restoring the previous rules if any errors are found, or the rules can't be
+checked, like the set command does. If the rules can't be saved, or hold errors, the file holding your changes is
+kept.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cube := args[0]
+		rules, err := getRules(cube)
+		if err != nil {
+			return err
+		}
+		file, err := os.CreateTemp("", "tm1ctl-*.rux")
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if _, err := file.WriteString(rules); err != nil {
+			return err
+		}
+
+		if err := editorCommand(file.Name()).Run(); err != nil {
+			os.Remove(file.Name())
+			return fmt.Errorf("unable to run editor: %w", err)
+		}
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, []byte(rules)) {
+			os.Remove(file.Name())
+			fmt.Println("Edit cancelled, no changes made.")
+			return nil
+		}
+		if err := updateRules(cube, string(edited)); err != nil {
+			fmt.Fprintf(os.Stderr, "Your changes have been kept in '%s'.\n", file.Name())
+			return err
+		}
+		os.Remove(file.Name())
+		return nil
+	},
+}
+
+var rulesCheckCmd = &cobra.Command{
+	Use:   "check <cube>",
+	Short: "Has the service check the saved rules of a cube and reports the errors found",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		errors, err := checkRules(args[0])
+		if err != nil {
+			return err
+		}
+		if len(errors) > 0 {
+			return reportRuleErrors(errors)
+		}
+		fmt.Printf("No errors found in the rules of cube '%s'.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(rulesGetCmd)
+	rulesGetCmd.Flags().StringVar(&rulesFile, "file", "", "The file to write the rules to, by default the rules are written to stdout")
+	rulesCmd.AddCommand(rulesGetCmd)
+
+	addDatabaseFlags(rulesSetCmd)
+	rulesSetCmd.Flags().StringVar(&rulesFile, "file", "", "The file holding the rules, '-' to read the rules from stdin")
+	rulesSetCmd.MarkFlagRequired("file")
+	rulesSetCmd.Flags().BoolVar(&rulesForce, "force", false, "Keep the rules, even if errors are found")
+	rulesCmd.AddCommand(rulesSetCmd)
+
+	addDatabaseFlags(rulesEditCmd)
+	rulesEditCmd.Flags().BoolVar(&rulesForce, "force", false, "Keep the rules, even if errors are found")
+	rulesCmd.AddCommand(rulesEditCmd)
+
+	addDatabaseFlags(rulesCheckCmd)
+	rulesCmd.AddCommand(rulesCheckCmd)
+
+	rootCmd.AddCommand(rulesCmd)
+}
@@ -0,0 +1,471 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	securityFriendlyName string
+	securityPassword     string
+	securityGroups       []string
+	securityFile         string
+	securityApply        bool
+	securityManageAdmins bool
+)
+
+// The actions making up the plan of the security sync command, in the order they are applied
+const (
+	securityCreateGroup  = "CreateGroup"
+	securityCreateUser   = "CreateUser"
+	securityAddMember    = "AddMember"
+	securityRemoveMember = "RemoveMember"
+	// Removals from admin groups are planned, separately and last, only using --manage-admin-groups
+	securityRemoveAdminMember = "RemoveAdminMember"
+)
+
+// The built-in groups granting administrative rights, by their name keys
+var securityAdminGroups = map[string]bool{
+	utils.NameKey("ADMIN"):           true,
+	utils.NameKey("SecurityAdmin"):   true,
+	utils.NameKey("DataAdmin"):       true,
+	utils.NameKey("OperationsAdmin"): true,
+}
+
+// securityCmd represents the security command
+var securityCmd = &cobra.Command{
+	Use:   "security",
+	Short: "Manage the users and groups of your TM1 database",
+}
+
+// securityUserCmd represents the security user command
+var securityUserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users of your TM1 database",
+}
+
+// securityGroupCmd represents the security group command
+var securityGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage the groups of your TM1 database, and their members",
+}
+
+// memberNames replaces the entities in the navigation property of the entity by their names
+func memberNames(entity map[string]any, property string) []string {
+	list, _ := entity[property].([]any)
+	names := make([]string, 0, len(list))
+	values := make([]any, 0, len(list))
+	for _, raw := range list {
+		if member, ok := raw.(map[string]any); ok {
+			names = append(names, utils.Stringify(member["Name"]))
+			values = append(values, member["Name"])
+		}
+	}
+	entity[property] = values
+	return names
+}
+
+// addMember adds the user to the group
+func addMember(userName, group string) error {
+	_, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Users"+utils.ODataKey(userName)+"/Groups/$ref",
+		map[string]any{"@odata.id": "Groups" + utils.ODataKey(group)})
+	return err
+}
+
+// removeMember removes the user from the group
+func removeMember(userName, group string) error {
+	id := strings.ReplaceAll(url.QueryEscape("Groups("+utils.ODataString(group)+")"), "+", "%20")
+	return utils.DatabaseAPIDelete(host, instance, database, user, password, "Users"+utils.ODataKey(userName)+"/Groups?$id="+id)
+}
+
+// updateMembers adds, or removes, the users to, or from, the group, reporting every change made
+func updateMembers(group string, users []string, update func(userName, group string) error, done string) error {
+	for _, userName := range users {
+		if err := update(userName, group); err != nil {
+			return err
+		}
+		fmt.Printf("User '%s' has been %s group '%s'.\n", userName, done, group)
+	}
+	return nil
+}
+
+// securityPlan returns the actions needed for the users and groups to match those in the security file. Groups
+// in the file, and users, are created if they don't exist yet, and the members of these groups are made to match the
+// file, removing the users not listed as members. The members of other groups are left untouched. Existing users and
+// groups are referred to by their names as known by the service, names being case and space insensitive. Users are
+// only removed from admin groups if manageAdmins is set, and the user tm1ctl is signed in as never is, the removals
+// not planned being returned separately.
+func securityPlan(users []utils.SecurityUser, manageAdmins bool) ([]any, []any, error) {
+	activeUser, err := utils.DatabaseAPIGet(host, instance, database, user, password, "ActiveUser?$select=Name")
+	if err != nil {
+		return nil, nil, err
+	}
+	activeKey := utils.NameKey(utils.Stringify(activeUser["Name"]))
+	data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Users?$select=Name&$expand=Groups($select=Name)")
+	if err != nil {
+		return nil, nil, err
+	}
+	current := make(map[string]map[string]bool)
+	currentNames := make(map[string]string)
+	list, _ := data["value"].([]any)
+	for _, raw := range list {
+		u, _ := raw.(map[string]any)
+		name := utils.Stringify(u["Name"])
+		key := utils.NameKey(name)
+		current[key] = make(map[string]bool)
+		currentNames[key] = name
+		for _, group := range memberNames(u, "Groups") {
+			current[key][utils.NameKey(group)] = true
+		}
+	}
+	data, err = utils.DatabaseAPIGet(host, instance, database, user, password, "Groups?$select=Name")
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[string]string)
+	list, _ = data["value"].([]any)
+	for _, raw := range list {
+		g, _ := raw.(map[string]any)
+		name := utils.Stringify(g["Name"])
+		groups[utils.NameKey(name)] = name
+	}
+
+	var createGroups, createUsers, addMembers, removeMembers, removeAdminMembers, kept []any
+	action := func(typ, userName, group string) map[string]any {
+		return map[string]any{"Action": typ, "User": userName, "Group": group}
+	}
+	managed := make(map[string]string)
+	desired := make(map[string]map[string]bool)
+	for _, u := range users {
+		key := utils.NameKey(u.Name)
+		if desired[key] == nil {
+			desired[key] = make(map[string]bool)
+		}
+		if _, ok := current[key]; !ok {
+			createUsers = append(createUsers, action(securityCreateUser, u.Name, ""))
+			current[key] = make(map[string]bool)
+			currentNames[key] = u.Name
+		}
+		for _, group := range u.Groups {
+			groupKey := utils.NameKey(group)
+			if _, ok := managed[groupKey]; !ok {
+				if name, ok := groups[groupKey]; ok {
+					managed[groupKey] = name
+				} else {
+					managed[groupKey] = group
+					createGroups = append(createGroups, action(securityCreateGroup, "", group))
+				}
+			}
+			if !desired[key][groupKey] && !current[key][groupKey] {
+				addMembers = append(addMembers, action(securityAddMember, currentNames[key], managed[groupKey]))
+			}
+			desired[key][groupKey] = true
+		}
+	}
+	for key, memberOf := range current {
+		for groupKey := range memberOf {
+			group, ok := managed[groupKey]
+			switch {
+			case !ok || desired[key][groupKey]:
+			case !securityAdminGroups[groupKey]:
+				removeMembers = append(removeMembers, action(securityRemoveMember, currentNames[key], group))
+			case key == activeKey:
+				kept = append(kept, map[string]any{"User": currentNames[key], "Group": group, "Reason": "tm1ctl is signed in as this user"})
+			case !manageAdmins:
+				kept = append(kept, map[string]any{"User": currentNames[key], "Group": group, "Reason": "use --manage-admin-groups to remove members of admin groups"})
+			default:
+				removeAdminMembers = append(removeAdminMembers, action(securityRemoveAdminMember, currentNames[key], group))
+			}
+		}
+	}
+	for _, actions := range [][]any{removeMembers, removeAdminMembers, kept} {
+		sort.SliceStable(actions, func(i, j int) bool {
+			a, b := actions[i].(map[string]any), actions[j].(map[string]any)
+			return a["User"].(string) < b["User"].(string) || a["User"] == b["User"] && a["Group"].(string) < b["Group"].(string)
+		})
+	}
+
+	plan := append(append([]any{}, createGroups...), createUsers...)
+	plan = append(plan, addMembers...)
+	plan = append(plan, removeMembers...)
+	return append(plan, removeAdminMembers...), kept, nil
+}
+
+// applySecurityAction applies an action of the plan of the security sync command
+func applySecurityAction(action map[string]any, friendlyNames map[string]string) error {
+	userName, group := utils.Stringify(action["User"]), utils.Stringify(action["Group"])
+	switch action["Action"] {
+	case securityCreateGroup:
+		_, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Groups", map[string]any{"Name": group})
+		return err
+	case securityCreateUser:
+		payload := map[string]any{"Name": userName}
+		if friendlyName := friendlyNames[utils.NameKey(userName)]; friendlyName != "" {
+			payload["FriendlyName"] = friendlyName
+		}
+		_, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Users", payload)
+		return err
+	case securityAddMember:
+		return addMember(userName, group)
+	case securityRemoveMember, securityRemoveAdminMember:
+		return removeMember(userName, group)
+	}
+	return nil
+}
+
+var securityUserListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of users, with the groups they are a member of",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Users?$select=Name,FriendlyName,Type,Enabled&$expand=Groups($select=Name)")
+			if err != nil {
+				return nil, err
+			}
+			users, _ := data["value"].([]any)
+			for _, raw := range users {
+				if u, ok := raw.(map[string]any); ok {
+					memberNames(u, "Groups")
+				}
+			}
+			return data, nil
+		}, "Name", "FriendlyName", "Type", "Enabled", "Groups")
+	},
+}
+
+var securityUserCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates a user, optionally making it a member of the groups specified",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payload := map[string]any{"Name": args[0]}
+		if securityFriendlyName != "" {
+			payload["FriendlyName"] = securityFriendlyName
+		}
+		if securityPassword != "" {
+			payload["Password"] = securityPassword
+		}
+		if len(securityGroups) > 0 {
+			binds := make([]string, len(securityGroups))
+			for i, group := range securityGroups {
+				binds[i] = "Groups" + utils.ODataKey(group)
+			}
+			payload["Groups@odata.bind"] = binds
+		}
+		if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Users", payload); err != nil {
+			return err
+		}
+		fmt.Printf("User '%s' has been created.\n", args[0])
+		return nil
+	},
+}
+
+var securityUserDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, "Users"+utils.ODataKey(args[0])); err != nil {
+			return err
+		}
+		fmt.Printf("User '%s' has been deleted.\n", args[0])
+		return nil
+	},
+}
+
+var securityGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of groups, with their members",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Groups?$select=Name&$expand=Users($select=Name)")
+			if err != nil {
+				return nil, err
+			}
+			groups, _ := data["value"].([]any)
+			for _, raw := range groups {
+				if g, ok := raw.(map[string]any); ok {
+					memberNames(g, "Users")
+				}
+			}
+			return data, nil
+		}, "Name", "Users")
+	},
+}
+
+var securityGroupCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates a group",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, "Groups", map[string]any{"Name": args[0]}); err != nil {
+			return err
+		}
+		fmt.Printf("Group '%s' has been created.\n", args[0])
+		return nil
+	},
+}
+
+var securityGroupDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a group",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, "Groups"+utils.ODataKey(args[0])); err != nil {
+			return err
+		}
+		fmt.Printf("Group '%s' has been deleted.\n", args[0])
+		return nil
+	},
+}
+
+var securityGroupAddMemberCmd = &cobra.Command{
+	Use:   "add-member <group> <user>...",
+	Short: "Adds users to a group",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateMembers(args[0], args[1:], addMember, "added to")
+	},
+}
+
+var securityGroupRemoveMemberCmd = &cobra.Command{
+	Use:   "remove-member <group> <user>...",
+	Short: "Removes users from a group",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateMembers(args[0], args[1:], removeMember, "removed from")
+	},
+}
+
+var securitySyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Shows, and using --apply applies, the changes needed for users and groups to match a security file",
+	Long: `Shows, and using --apply applies, the changes needed for the users and groups to match those in a security file,
+like an export of your identity source. Users and groups in the file that don't exist yet are created, and the members
+of the groups in the file are made to match the file: users are added to, and removed from, these groups as needed.
+Groups that aren't in the file, and their members, are left untouched, as are users, which are never deleted.
+
+Members of the built-in admin groups, ADMIN, SecurityAdmin, DataAdmin and OperationsAdmin, are only removed from
+these groups using --manage-admin-groups, these removals being listed separately, as RemoveAdminMember, at the end
+of the plan. The user tm1ctl is signed in as is never removed from an admin group, so it doesn't lock itself out.
+
+Files with the .csv extension are read as CSV files, holding a Name, or User, column, an optional FriendlyName column,
+and either a Groups column, separating groups by semicolons, or a Group column, having a row per membership. All
+other files are read as YAML, or JSON, files holding a list of users, each with a Name, FriendlyName and Groups.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, err := utils.ReadSecurityFile(securityFile)
+		if err != nil {
+			return err
+		}
+		plan, kept, err := securityPlan(users, securityManageAdmins)
+		if err != nil {
+			return err
+		}
+		for _, raw := range kept {
+			k := raw.(map[string]any)
+			fmt.Fprintf(os.Stderr, "Warning: user '%s' is not removed from admin group '%s', %s\n", k["User"], k["Group"], k["Reason"])
+		}
+		if len(plan) == 0 && viper.GetString("output-format") == "table" {
+			fmt.Println("No changes needed, the users and groups match the security file.")
+			return nil
+		}
+		if !securityApply {
+			if err := utils.OutputRows(plan, "Action", "User", "Group"); err != nil {
+				return err
+			}
+			if viper.GetString("output-format") == "table" {
+				admins := 0
+				for _, raw := range plan {
+					if raw.(map[string]any)["Action"] == securityRemoveAdminMember {
+						admins++
+					}
+				}
+				if admins > 0 {
+					fmt.Printf("\n%d changes are needed, of which %d, listed as %s, remove members from admin groups, use --apply to apply them.\n",
+						len(plan), admins, securityRemoveAdminMember)
+				} else {
+					fmt.Printf("\n%d changes are needed, use --apply to apply them.\n", len(plan))
+				}
+			}
+			return nil
+		}
+
+		friendlyNames := make(map[string]string)
+		for _, u := range users {
+			friendlyNames[utils.NameKey(u.Name)] = u.FriendlyName
+		}
+		failed := 0
+		for _, raw := range plan {
+			action := raw.(map[string]any)
+			action["Status"] = "Done"
+			if err := applySecurityAction(action, friendlyNames); err != nil {
+				// Only continue with the next action if the failure isn't one that would fail any other action as well
+				if code := utils.ExitCode(err); code == utils.ExitAuth || code == utils.ExitConnection {
+					return err
+				}
+				action["Status"] = err.Error()
+				failed++
+			}
+		}
+		if err := utils.OutputRows(plan, "Action", "User", "Group", "Status"); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d changes failed", failed, len(plan))
+		}
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(securityUserListCmd)
+	securityUserCmd.AddCommand(securityUserListCmd)
+
+	addDatabaseFlags(securityUserCreateCmd)
+	securityUserCreateCmd.Flags().StringVar(&securityFriendlyName, "friendly-name", "", "The friendly name of the user")
+	securityUserCreateCmd.Flags().StringVar(&securityPassword, "initial-password", "", "The password of the user, if using TM1 native security")
+	securityUserCreateCmd.Flags().StringArrayVar(&securityGroups, "group", nil, "A group to make the user a member of, can be repeated")
+	securityUserCmd.AddCommand(securityUserCreateCmd)
+
+	addDatabaseFlags(securityUserDeleteCmd)
+	securityUserCmd.AddCommand(securityUserDeleteCmd)
+
+	securityCmd.AddCommand(securityUserCmd)
+
+	addDatabaseFlags(securityGroupListCmd)
+	securityGroupCmd.AddCommand(securityGroupListCmd)
+
+	addDatabaseFlags(securityGroupCreateCmd)
+	securityGroupCmd.AddCommand(securityGroupCreateCmd)
+
+	addDatabaseFlags(securityGroupDeleteCmd)
+	securityGroupCmd.AddCommand(securityGroupDeleteCmd)
+
+	addDatabaseFlags(securityGroupAddMemberCmd)
+	securityGroupCmd.AddCommand(securityGroupAddMemberCmd)
+
+	addDatabaseFlags(securityGroupRemoveMemberCmd)
+	securityGroupCmd.AddCommand(securityGroupRemoveMemberCmd)
+
+	securityCmd.AddCommand(securityGroupCmd)
+
+	addDatabaseFlags(securitySyncCmd)
+	securitySyncCmd.Flags().StringVar(&securityFile, "file", "", "The CSV, YAML or JSON file holding the users and their groups, '-' to read YAML, or JSON, from stdin")
+	securitySyncCmd.MarkFlagRequired("file")
+	securitySyncCmd.Flags().BoolVar(&securityApply, "apply", false, "Apply the changes, instead of only showing them")
+	securitySyncCmd.Flags().BoolVar(&securityManageAdmins, "manage-admin-groups", false, "Remove members of the admin groups in the file that aren't listed as such as well")
+	securityCmd.AddCommand(securitySyncCmd)
+
+	rootCmd.AddCommand(securityCmd)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// The properties retrieved for the session list command, including the user and threads of the sessions
+const sessionQuery = "$select=ID,Context,Active&$expand=User($select=Name),Threads($select=ID,State,RLocks,IXLocks,WLocks,ElapsedTime,WaitTime)"
+
+var sessionColumns = []string{"ID", "User", "Context", "Active", "Threads", "State", "Locks", "ElapsedTime", "WaitTime"}
+
+// sessionCmd represents the session command
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Monitor and close the sessions of your TM1 database",
+}
+
+// summarizeSession replaces the user of the session by its name and its threads by their number, summarizing the
+// threads as the state of the first thread that isn't idle, the locks held by all threads and the longest elapsed
+// and wait time, in seconds
+func summarizeSession(session map[string]any) {
+	if u, ok := session["User"].(map[string]any); ok {
+		session["User"] = u["Name"]
+	}
+	threads, _ := session["Threads"].([]any)
+	state := "Idle"
+	var r, ix, w int
+	var elapsed, wait float64
+	for _, raw := range threads {
+		thread, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		if s := utils.Stringify(thread["State"]); state == "Idle" && s != "" {
+			state = s
+		}
+		tr, tix, tw := threadLocks(thread)
+		r, ix, w = r+tr, ix+tix, w+tw
+		elapsed = max(elapsed, threadDuration(thread["ElapsedTime"]))
+		wait = max(wait, threadDuration(thread["WaitTime"]))
+	}
+	session["Threads"] = len(threads)
+	session["State"] = state
+	session["Locks"] = formatLocks(r, ix, w)
+	session["ElapsedTime"] = elapsed
+	session["WaitTime"] = wait
+}
+
+var sessionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of sessions, with the user, state, locks and elapsed and wait time, in seconds, of their threads",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Sessions?"+sessionQuery)
+			if err != nil {
+				return nil, err
+			}
+			filterCollection(data, func(session map[string]any) bool {
+				summarizeSession(session)
+				return matchesFilter(session["User"], threadOwner) && matchesFilter(session["State"], threadState)
+			})
+			return data, nil
+		}, sessionColumns...)
+	},
+}
+
+var sessionCloseCmd = &cobra.Command{
+	Use:   "close <id>",
+	Short: "Closes the session, cancelling any operation its threads are executing",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, fmt.Sprintf("Sessions(%d)", id), "Close"), map[string]any{}); err != nil {
+			return err
+		}
+		fmt.Printf("Session %d has been closed.\n", id)
+		return nil
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(sessionListCmd)
+	addThreadFilterFlags(sessionListCmd)
+	sessionCmd.AddCommand(sessionListCmd)
+
+	addDatabaseFlags(sessionCloseCmd)
+	sessionCmd.AddCommand(sessionCloseCmd)
+
+	rootCmd.AddCommand(sessionCmd)
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	subsetPrivate bool
+	subsetMDX     string
+)
+
+// subsetCmd represents the subset command
+var subsetCmd = &cobra.Command{
+	Use:   "subset",
+	Short: "Manage the public and private subsets of a hierarchy",
+}
+
+// subsetsPath returns the path to the public, or the current user's private, subsets of the hierarchy
+func subsetsPath(dimension, hierarchy string, private bool) string {
+	if private {
+		return hierarchyPath(dimension, hierarchy) + "/PrivateSubsets"
+	}
+	return hierarchyPath(dimension, hierarchy) + "/Subsets"
+}
+
+// summarizeSubset adds the type of the subset, 'Dynamic' if it is defined by an MDX expression or 'Static'
+// otherwise, and its visibility
+func summarizeSubset(subset map[string]any, private bool) {
+	subset["Type"] = "Static"
+	if expression, _ := subset["Expression"].(string); strings.TrimSpace(expression) != "" {
+		subset["Type"] = "Dynamic"
+	}
+	subset["Visibility"] = "Public"
+	if private {
+		subset["Visibility"] = "Private"
+	}
+}
+
+var subsetListCmd = &cobra.Command{
+	Use:   "list <dimension>",
+	Short: "Get the list of public subsets, and your private subsets, of a hierarchy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			var subsets []any
+			for _, private := range []bool{false, true} {
+				data, err := utils.DatabaseAPIGet(host, instance, database, user, password, subsetsPath(args[0], hierarchyName, private)+"?$select=Name,Expression")
+				if err != nil {
+					return nil, err
+				}
+				list, _ := data["value"].([]any)
+				for _, raw := range list {
+					if subset, ok := raw.(map[string]any); ok {
+						summarizeSubset(subset, private)
+						subsets = append(subsets, subset)
+					}
+				}
+			}
+			return map[string]any{"value": subsets}, nil
+		}, "Name", "Type", "Visibility", "Expression")
+	},
+}
+
+var subsetShowCmd = &cobra.Command{
+	Use:   "show <dimension> <subset>",
+	Short: "Show the definition of a subset and its members, resolving a dynamic subset to its current members",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := subsetsPath(args[0], hierarchyName, subsetPrivate) + utils.ODataKey(args[1]) + "?$select=Name,Expression,Alias&$expand=Elements($select=Name)"
+		subset, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+		if err != nil {
+			return err
+		}
+		summarizeSubset(subset, subsetPrivate)
+		elements, _ := subset["Elements"].([]any)
+		names := make([]string, 0, len(elements))
+		for _, raw := range elements {
+			if element, ok := raw.(map[string]any); ok {
+				names = append(names, utils.Stringify(element["Name"]))
+			}
+		}
+
+		if viper.GetString("output-format") != "table" {
+			subset["Elements"] = names
+			return utils.OutputEntity(subset)
+		}
+
+		if err := utils.OutputEntity(subset, "Name", "Type", "Visibility", "Alias", "Expression"); err != nil {
+			return err
+		}
+		rows := make([]any, len(names))
+		for i, name := range names {
+			rows[i] = map[string]any{"Position": i + 1, "Element": name}
+		}
+		fmt.Println()
+		fmt.Println("Elements:")
+		return utils.OutputRows(rows, "Position", "Element")
+	},
+}
+
+var subsetCreateCmd = &cobra.Command{
+	Use:   "create <dimension> <subset> [element...]",
+	Short: "Creates a static subset holding the elements specified, or a dynamic subset using --mdx",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if subsetMDX != "" {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.MinimumNArgs(3)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dimension, hierarchy := args[0], hierarchyName
+		if hierarchy == "" {
+			hierarchy = dimension
+		}
+		payload := map[string]any{"Name": args[1], "Hierarchy@odata.bind": hierarchyPath(dimension, hierarchy)}
+		if subsetMDX != "" {
+			payload["Expression"] = subsetMDX
+		} else {
+			elements := make([]any, 0, len(args)-2)
+			for _, element := range args[2:] {
+				elements = append(elements, utils.ElementPath(dimension, hierarchy, element))
+			}
+			payload["Elements@odata.bind"] = elements
+		}
+		if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, subsetsPath(dimension, hierarchy, subsetPrivate), payload); err != nil {
+			return err
+		}
+		kind := "static"
+		if subsetMDX != "" {
+			kind = "dynamic"
+		}
+		fmt.Printf("Subset '%s' has been created on hierarchy '%s' as a %s subset.\n", args[1], hierarchy, kind)
+		return nil
+	},
+}
+
+var subsetDeleteCmd = &cobra.Command{
+	Use:   "delete <dimension> <subset>",
+	Short: "Deletes a subset from the hierarchy",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, subsetsPath(args[0], hierarchyName, subsetPrivate)+utils.ODataKey(args[1])); err != nil {
+			return err
+		}
+		fmt.Printf("Subset '%s' has been deleted from dimension '%s'!\n", args[1], args[0])
+		return nil
+	},
+}
+
+// addPrivateSubsetFlag adds the flag selecting the private, instead of the public, subset to the command
+func addPrivateSubsetFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&subsetPrivate, "private", false, "Use your private subset instead of the public subset")
+}
+
+func init() {
+
+	addDatabaseFlags(subsetListCmd)
+	addHierarchyFlag(subsetListCmd)
+	subsetCmd.AddCommand(subsetListCmd)
+
+	addDatabaseFlags(subsetShowCmd)
+	addHierarchyFlag(subsetShowCmd)
+	addPrivateSubsetFlag(subsetShowCmd)
+	subsetCmd.AddCommand(subsetShowCmd)
+
+	addDatabaseFlags(subsetCreateCmd)
+	addHierarchyFlag(subsetCreateCmd)
+	addPrivateSubsetFlag(subsetCreateCmd)
+	subsetCreateCmd.Flags().StringVar(&subsetMDX, "mdx", "", "The MDX expression defining a dynamic subset")
+	subsetCmd.AddCommand(subsetCreateCmd)
+
+	addDatabaseFlags(subsetDeleteCmd)
+	addHierarchyFlag(subsetDeleteCmd)
+	addPrivateSubsetFlag(subsetDeleteCmd)
+	subsetCmd.AddCommand(subsetDeleteCmd)
+
+	rootCmd.AddCommand(subsetCmd)
+}
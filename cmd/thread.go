@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	threadOwner string
+	threadState string
+)
+
+// The properties retrieved for, and shown by, the thread list command
+const threadQuery = "$select=ID,Type,Name,Context,State,Function,ObjectType,ObjectName,RLocks,IXLocks,WLocks,ElapsedTime,WaitTime,Info"
+
+var threadColumns = []string{"ID", "User", "Context", "State", "Function", "ObjectName", "Locks", "ElapsedTime", "WaitTime"}
+
+// threadCmd represents the thread command
+var threadCmd = &cobra.Command{
+	Use:   "thread",
+	Short: "Monitor and cancel the threads running in your TM1 database",
+}
+
+// threadDuration returns the duration, as returned by the service as an ISO 8601 duration, in seconds
+func threadDuration(value any) float64 {
+	d, _ := parseDuration(isoDurationRegexp, utils.Stringify(value))
+	return d.Seconds()
+}
+
+// threadLocks returns the number of read, intent exclusive and write locks held by the thread
+func threadLocks(thread map[string]any) (int, int, int) {
+	count := func(name string) int {
+		f, _ := thread[name].(float64)
+		return int(f)
+	}
+	return count("RLocks"), count("IXLocks"), count("WLocks")
+}
+
+// formatLocks returns the read, intent exclusive and write locks as a single value
+func formatLocks(r, ix, w int) string {
+	return fmt.Sprintf("R:%d IX:%d W:%d", r, ix, w)
+}
+
+// summarizeThread names the user running the thread as such, replaces the elapsed and wait time by their number of
+// seconds and adds the locks held by the thread as a single value
+func summarizeThread(thread map[string]any) {
+	thread["User"] = thread["Name"]
+	thread["ElapsedTime"] = threadDuration(thread["ElapsedTime"])
+	thread["WaitTime"] = threadDuration(thread["WaitTime"])
+	thread["Locks"] = formatLocks(threadLocks(thread))
+}
+
+// matchesFilter returns true if the value matches the filter, ignoring case, an empty filter matching any value
+func matchesFilter(value any, filter string) bool {
+	return filter == "" || strings.EqualFold(utils.Stringify(value), filter)
+}
+
+// filterCollection keeps the entities in the collection for which keep returns true
+func filterCollection(data map[string]any, keep func(entity map[string]any) bool) {
+	list, _ := data["value"].([]any)
+	kept := make([]any, 0, len(list))
+	for _, raw := range list {
+		if entity, ok := raw.(map[string]any); ok && keep(entity) {
+			kept = append(kept, entity)
+		}
+	}
+	data["value"] = kept
+}
+
+// parseID parses the ID of a thread or session
+func parseID(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, utils.UsageError("invalid ID '%s', expected a number", id)
+	}
+	return n, nil
+}
+
+var threadListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Get the list of threads, with the user, state, locks and elapsed and wait time, in seconds",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			data, err := utils.DatabaseAPIGet(host, instance, database, user, password, "Threads?"+threadQuery)
+			if err != nil {
+				return nil, err
+			}
+			filterCollection(data, func(thread map[string]any) bool {
+				summarizeThread(thread)
+				return matchesFilter(thread["User"], threadOwner) && matchesFilter(thread["State"], threadState)
+			})
+			return data, nil
+		}, threadColumns...)
+	},
+}
+
+var threadCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancels the operation the thread is executing",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := utils.DatabaseAPIPost(host, instance, database, user, password, utils.DatabaseActionPath(host, instance, database, user, password, fmt.Sprintf("Threads(%d)", id), "CancelOperation"), map[string]any{}); err != nil {
+			return err
+		}
+		fmt.Printf("The operation of thread %d has been cancelled.\n", id)
+		return nil
+	},
+}
+
+// addThreadFilterFlags adds the flags filtering the threads, or sessions, listed by user and state
+func addThreadFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&threadOwner, "owner", "", "Only list those of the specified user")
+	cmd.Flags().StringVar(&threadState, "state", "", "Only list those in the specified state, like Run, Wait or Idle")
+}
+
+func init() {
+
+	addDatabaseFlags(threadListCmd)
+	addThreadFilterFlags(threadListCmd)
+	threadCmd.AddCommand(threadListCmd)
+
+	addDatabaseFlags(threadCancelCmd)
+	threadCmd.AddCommand(threadCancelCmd)
+
+	rootCmd.AddCommand(threadCmd)
+}
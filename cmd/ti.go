@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tiFile      string
+	tiParams    []string
+	tiNumParams []string
+)
+
+// The name of the unbound process executing the TurboIntegrator code
+const tiProcessName = "tm1ctl ti run"
+
+// tiCmd represents the ti command
+var tiCmd = &cobra.Command{
+	Use:   "ti",
+	Short: "Execute TurboIntegrator code against your TM1 database",
+}
+
+// tiParameters returns the parameter definitions of the unbound process and the parameters to execute it with, from
+// the 'name=value' pairs of the string parameters and those of the numeric parameters
+func tiParameters(texts, numbers []string) ([]any, []any, error) {
+	definitions := make([]any, 0, len(texts)+len(numbers))
+	parameters := make([]any, 0, len(texts)+len(numbers))
+	defined := make(map[string]bool)
+	add := func(pair, flag string, numeric bool) error {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return utils.UsageError("invalid %s '%s', expected 'name=value'", flag, pair)
+		}
+		if defined[utils.NameKey(name)] {
+			return utils.UsageError("parameter '%s' is specified more than once", name)
+		}
+		defined[utils.NameKey(name)] = true
+		var val any = value
+		typ := "String"
+		if numeric {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return utils.UsageError("invalid %s '%s', '%s' is not a number", flag, pair, value)
+			}
+			val = number
+			typ = "Numeric"
+		}
+		definitions = append(definitions, map[string]any{"Name": name, "Prompt": "", "Value": val, "Type": typ})
+		parameters = append(parameters, map[string]any{"Name": name, "Value": val})
+		return nil
+	}
+	for _, pair := range texts {
+		if err := add(pair, "param", false); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, pair := range numbers {
+		if err := add(pair, "param-num", true); err != nil {
+			return nil, nil, err
+		}
+	}
+	return definitions, parameters, nil
+}
+
+var tiRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Executes TurboIntegrator code, read from a file or stdin, without creating a process",
+	Long: `Executes TurboIntegrator code, read from the file specified using --file or otherwise from stdin, as the prolog
+of an unbound process, one that only exists for the duration of its execution, leaving nothing behind on the server.
+Parameters, available to the code as variables, are passed as strings using --param name=value and as numbers using
+--param-num name=value, both of which can be repeated. The status the code completed with is reported and, if it did not
+complete successfully, its error log, if any, is shown and tm1ctl exits with exit code 9.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var code []byte
+		var err error
+		if tiFile == "" || tiFile == "-" {
+			code, err = io.ReadAll(os.Stdin)
+		} else {
+			code, err = os.ReadFile(tiFile)
+		}
+		if err != nil {
+			return utils.UsageError("unable to read code: %v", err)
+		}
+		if strings.TrimSpace(string(code)) == "" {
+			return utils.UsageError("no code specified, use --file or pass the code on stdin")
+		}
+		definitions, parameters, err := tiParameters(tiParams, tiNumParams)
+		if err != nil {
+			return err
+		}
+
+		process := map[string]any{
+			"Name":              tiProcessName,
+			"HasSecurityAccess": false,
+			"PrologProcedure":   string(code),
+			"MetadataProcedure": "",
+			"DataProcedure":     "",
+			"EpilogProcedure":   "",
+			"Parameters":        definitions,
+		}
+		return executeProcess(tiProcessName, "ExecuteProcessWithReturn", map[string]any{"Process": process, "Parameters": parameters})
+	},
+}
+
+func init() {
+
+	addDatabaseFlags(tiRunCmd)
+	tiRunCmd.Flags().StringVar(&tiFile, "file", "", "The file holding the code, by default, or if '-', the code is read from stdin")
+	tiRunCmd.Flags().StringArrayVar(&tiParams, "param", nil, "A string parameter to pass, as 'name=value', can be repeated")
+	tiRunCmd.Flags().StringArrayVar(&tiNumParams, "param-num", nil, "A numeric parameter to pass, as 'name=value', can be repeated")
+	tiCmd.AddCommand(tiRunCmd)
+
+	rootCmd.AddCommand(tiCmd)
+}
@@ -25,29 +25,25 @@ var userListCmd = &cobra.Command{
 	Use:   "list [name]",
 	Short: "List all specified users",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		users := viper.GetStringMap("users")
 
 		if len(users) == 0 {
 			fmt.Println("No users specified.")
-			return
+			return nil
 		}
 
 		if len(args) == 1 && args[0] != "" {
 			name := args[0]
 			user := users[name]
 			if user == nil {
-				fmt.Printf("no details specified for user '%s'\n", name)
-				return
+				return utils.NotFoundError("no details specified for user '%s'", name)
 			}
-			err := utils.OutputMap(user.(map[string]any), "Name")
-			cobra.CheckErr(err)
-			return
+			return utils.OutputMap(user.(map[string]any), "Name")
 		}
 
 		// TODO: Highlight/mark the one that's active
-		err := utils.OutputMap(users, "Name")
-		cobra.CheckErr(err)
+		return utils.OutputMap(users, "Name")
 	},
 }
 
@@ -55,7 +51,7 @@ var userSetCmd = &cobra.Command{
 	Use:   "set <name>",
 	Short: "Set credential or session variables for the specified user",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
 		// Validate or initialize the users entry
@@ -68,8 +64,7 @@ var userSetCmd = &cobra.Command{
 		} else {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid user format for '%s'\n", name)
-				return
+				return utils.ConfigError("invalid user format for '%s'", name)
 			}
 			userMap = cast
 		}
@@ -101,8 +96,7 @@ var userSetCmd = &cobra.Command{
 			var varMap map[string]any
 			err := json.Unmarshal([]byte(userVariables), &varMap)
 			if err != nil {
-				fmt.Printf("Value specified for variables is not a valid map")
-				return
+				return utils.UsageError("value specified for variables is not a valid map: %v", err)
 			}
 			userMap["variables"] = varMap
 			changed = true
@@ -114,14 +108,16 @@ var userSetCmd = &cobra.Command{
 		}
 
 		if !changed {
-			fmt.Println("No values provided to set. Use --name, --password or --variables.")
-			return
+			return utils.UsageError("no values provided to set, use --name, --password or --variables")
 		}
 
 		users[name] = userMap
 		viper.Set("users", users)
-		cobra.CheckErr(utils.SaveConfiguration())
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("Updated user '%s'\n", name)
+		return nil
 	},
 }
 
@@ -134,11 +130,13 @@ var userVariableListCmd = &cobra.Command{
 	Use:   "list [key]",
 	Short: "List all the user's session variables specified",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// No user specified then use active user
 		user, err := utils.GetUserName(userName)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
 
 		// Validate or initialize the users entry
 		users := viper.GetStringMap("users")
@@ -147,23 +145,17 @@ var userVariableListCmd = &cobra.Command{
 		if raw != nil {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid user format for '%s'\n", user)
-				return
+				return utils.ConfigError("invalid user format for '%s'", user)
 			}
 			raw = cast["variables"]
 		}
 
 		var varMap map[string]any
 
-		if raw == nil {
-			if len(args) == 1 && args[0] != "" {
-				fmt.Printf("No value set for variable '%s'", args[0])
-			}
-		} else {
+		if raw != nil {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid user variables format for '%s'\n", user)
-				return
+				return utils.ConfigError("invalid user variables format for '%s'", user)
 			}
 			varMap = cast
 		}
@@ -171,11 +163,10 @@ var userVariableListCmd = &cobra.Command{
 		if len(args) == 1 && args[0] != "" {
 			key := args[0]
 			val := varMap[key]
-			if val != nil && val != "" {
-				fmt.Printf("%s = %s\n", key, utils.Stringify(val))
-			} else {
-				fmt.Printf("No value set for variable '%s'", key)
+			if val == nil || val == "" {
+				return utils.NotFoundError("no value set for variable '%s'", key)
 			}
+			fmt.Printf("%s = %s\n", key, utils.Stringify(val))
 
 		} else {
 			for key, val := range varMap {
@@ -184,6 +175,7 @@ var userVariableListCmd = &cobra.Command{
 				}
 			}
 		}
+		return nil
 	},
 }
 
@@ -191,11 +183,13 @@ var userVariableSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set a user's session variable to the specified value",
 	Args:  cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// No user specified then use active user
 		user, err := utils.GetUserName(userName)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
 
 		// Validate or initialize the users entry
 		users := viper.GetStringMap("users")
@@ -207,8 +201,7 @@ var userVariableSetCmd = &cobra.Command{
 		} else {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid user format for '%s'\n", user)
-				return
+				return utils.ConfigError("invalid user format for '%s'", user)
 			}
 			userMap = cast
 		}
@@ -221,8 +214,7 @@ var userVariableSetCmd = &cobra.Command{
 		} else {
 			cast, ok := raw.(map[string]any)
 			if !ok {
-				fmt.Printf("Invalid user variables format for '%s'\n", user)
-				return
+				return utils.ConfigError("invalid user variables format for '%s'", user)
 			}
 			varMap = cast
 		}
@@ -240,8 +232,11 @@ var userVariableSetCmd = &cobra.Command{
 		userMap["variables"] = varMap
 		users[user] = userMap
 		viper.Set("users", users)
-		cobra.CheckErr(utils.SaveConfiguration())
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("Set variable %s to %s for user %s\n", key, utils.Stringify(value), user)
+		return nil
 	},
 }
 
@@ -249,23 +244,27 @@ var userUseCmd = &cobra.Command{
 	Use:   "use [name]",
 	Short: "Switch to using the specified user, or unset if no name given",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 1 && args[0] != "" {
 			name := args[0]
 
 			users := viper.GetStringMap("users")
 			if _, exists := users[name]; !exists {
-				fmt.Printf("User '%s' is not defined. Please configure a user before making it the active user.\n", name)
-				return
+				return utils.NotFoundError("user '%s' is not defined, please configure a user before making it the active user", name)
 			}
 			viper.Set("user", name)
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Printf("Set active user to '%s'.\n", name)
 		} else {
 			viper.Set("user", "")
-			cobra.CheckErr(utils.SaveConfiguration())
+			if err := utils.SaveConfiguration(); err != nil {
+				return err
+			}
 			fmt.Println("Reset active user.")
 		}
+		return nil
 	},
 }
 
@@ -273,13 +272,12 @@ var userDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Delete user from the list of, configured, users",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
 		users := viper.GetStringMap("users")
 		if _, ok := users[name]; !ok {
-			fmt.Printf("User '%s' does not exist.\n", name)
-			return
+			return utils.NotFoundError("user '%s' does not exist", name)
 		}
 
 		// Unset user if we're deleting the active one
@@ -292,8 +290,11 @@ var userDeleteCmd = &cobra.Command{
 		// Delete the user from the list of users
 		delete(users, name)
 		viper.Set("users", users)
-		cobra.CheckErr(utils.SaveConfiguration())
+		if err := utils.SaveConfiguration(); err != nil {
+			return err
+		}
 		fmt.Printf("Deleted user '%s'.\n", name)
+		return nil
 	},
 }
 
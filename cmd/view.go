@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hubert-Heijkers/tm1ctl/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	viewPrivate    bool
+	viewMDXFile    string
+	viewSkipZeroes bool
+	viewValues     string
+)
+
+// The subset properties retrieved for every axis of a native view
+const viewSubsetQuery = "Subset($select=Name,Expression;$expand=Hierarchy($select=Name;$expand=Dimension($select=Name)))"
+
+// The expansion of the axes of a native view, retrieved by the view show command
+const nativeViewExpand = "$expand=tm1.NativeView/Columns/" + viewSubsetQuery + ",tm1.NativeView/Rows/" + viewSubsetQuery +
+	",tm1.NativeView/Titles/" + viewSubsetQuery + ",tm1.NativeView/Titles/Selected($select=Name)"
+
+// viewCmd represents the view command
+var viewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Manage and execute the public and private views of a cube",
+}
+
+// viewsPath returns the path to the public, or the current user's private, views of the cube
+func viewsPath(cube string, private bool) string {
+	if private {
+		return "Cubes" + utils.ODataKey(cube) + "/PrivateViews"
+	}
+	return "Cubes" + utils.ODataKey(cube) + "/Views"
+}
+
+// viewType returns the type of the view, either 'MDX' or 'Native', as derived from its type annotation
+func viewType(view map[string]any) string {
+	name := utils.Stringify(view["@odata.type"])
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "View")
+}
+
+// viewVisibility returns 'Private' or 'Public' as the description of the visibility of a view
+func viewVisibility(private bool) string {
+	if private {
+		return "Private"
+	}
+	return "Public"
+}
+
+// viewAxisRows returns the rows describing the selections on the axis of a native view
+func viewAxisRows(axis string, selections any) []any {
+	list, _ := selections.([]any)
+	rows := make([]any, 0, len(list))
+	for _, raw := range list {
+		selection, _ := raw.(map[string]any)
+		subset, _ := selection["Subset"].(map[string]any)
+		hierarchy, _ := subset["Hierarchy"].(map[string]any)
+		dimension, _ := hierarchy["Dimension"].(map[string]any)
+		row := map[string]any{
+			"Axis":       axis,
+			"Dimension":  dimension["Name"],
+			"Hierarchy":  hierarchy["Name"],
+			"Subset":     subset["Name"],
+			"Expression": subset["Expression"],
+		}
+		if selected, ok := selection["Selected"].(map[string]any); ok {
+			row["Selected"] = selected["Name"]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+var viewListCmd = &cobra.Command{
+	Use:   "list <cube>",
+	Short: "Get the list of public views, and your private views, of a cube",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.OutputCollectionFrom(func() (map[string]any, error) {
+			var views []any
+			for _, private := range []bool{false, true} {
+				data, err := utils.DatabaseAPIGet(host, instance, database, user, password, viewsPath(args[0], private)+"?$select=Name")
+				if err != nil {
+					return nil, err
+				}
+				list, _ := data["value"].([]any)
+				for _, raw := range list {
+					if view, ok := raw.(map[string]any); ok {
+						view["Type"] = viewType(view)
+						view["Visibility"] = viewVisibility(private)
+						views = append(views, view)
+					}
+				}
+			}
+			return map[string]any{"value": views}, nil
+		}, "Name", "Type", "Visibility")
+	},
+}
+
+var viewShowCmd = &cobra.Command{
+	Use:   "show <cube> <view>",
+	Short: "Show the definition of a view, the MDX of an MDX view or the axes of a native view",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := viewsPath(args[0], viewPrivate) + utils.ODataKey(args[1])
+		view, err := utils.DatabaseAPIGet(host, instance, database, user, password, path)
+		if err != nil {
+			return err
+		}
+		typeName := viewType(view)
+		if typeName == "Native" {
+			if view, err = utils.DatabaseAPIGet(host, instance, database, user, password, path+"?"+nativeViewExpand); err != nil {
+				return err
+			}
+		}
+		view["Type"] = typeName
+		view["Visibility"] = viewVisibility(viewPrivate)
+
+		if viper.GetString("output-format") != "table" {
+			return utils.OutputEntity(view)
+		}
+
+		if err := utils.OutputEntity(view, "Name", "Type", "Visibility"); err != nil {
+			return err
+		}
+		fmt.Println()
+		if typeName != "Native" {
+			fmt.Println("MDX:")
+			fmt.Println(strings.TrimSpace(utils.Stringify(view["MDX"])))
+			return nil
+		}
+		var rows []any
+		rows = append(rows, viewAxisRows("Columns", view["Columns"])...)
+		rows = append(rows, viewAxisRows("Rows", view["Rows"])...)
+		rows = append(rows, viewAxisRows("Titles", view["Titles"])...)
+		fmt.Println("Axes:")
+		return utils.OutputRows(rows, "Axis", "Dimension", "Hierarchy", "Subset", "Expression", "Selected")
+	},
+}
+
+var viewExecuteCmd = &cobra.Command{
+	Use:   "execute <cube> <view>",
+	Short: "Execute a view and show the resulting cellset",
+	Long: `Execute a view and show the resulting cellset, the same way the mdx command shows the cellset of an MDX
+query: in table format as a grid, in csv and ndjson formats as a record per cell and in json format as returned by
+the service.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatted, err := formattedValues(viewValues)
+		if err != nil {
+			return err
+		}
+		cellset, err := utils.ExecuteView(host, instance, database, user, password, viewsPath(args[0], viewPrivate)+utils.ODataKey(args[1]), "")
+		if err != nil {
+			return err
+		}
+		return utils.OutputCellset(cellset, viewSkipZeroes, formatted)
+	},
+}
+
+var viewCreateCmd = &cobra.Command{
+	Use:   "create <cube> <view>",
+	Short: "Creates a new MDX view on the cube using the MDX query in the file specified",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mdx, err := readQuery(nil, viewMDXFile)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(mdx) == "" {
+			return utils.UsageError("the query is empty")
+		}
+		payload := map[string]any{"@odata.type": "#ibm.tm1.api.v1.MDXView", "Name": args[1], "MDX": mdx}
+		data, err := utils.DatabaseAPIPost(host, instance, database, user, password, viewsPath(args[0], viewPrivate), payload)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s view '%s' has been created on cube '%s'.\n", viewVisibility(viewPrivate), utils.Stringify(data["Name"]), args[0])
+		return nil
+	},
+}
+
+var viewDeleteCmd = &cobra.Command{
+	Use:   "delete <cube> <view>",
+	Short: "Deletes a view from the cube",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.DatabaseAPIDelete(host, instance, database, user, password, viewsPath(args[0], viewPrivate)+utils.ODataKey(args[1])); err != nil {
+			return err
+		}
+		fmt.Printf("%s view '%s' has been deleted from cube '%s'!\n", viewVisibility(viewPrivate), args[1], args[0])
+		return nil
+	},
+}
+
+// addPrivateViewFlag adds the flag selecting the private, instead of the public, view to the command
+func addPrivateViewFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&viewPrivate, "private", false, "Use your private view instead of the public view")
+}
+
+func init() {
+
+	addDatabaseFlags(viewListCmd)
+	viewCmd.AddCommand(viewListCmd)
+
+	addDatabaseFlags(viewShowCmd)
+	addPrivateViewFlag(viewShowCmd)
+	viewCmd.AddCommand(viewShowCmd)
+
+	addDatabaseFlags(viewExecuteCmd)
+	addPrivateViewFlag(viewExecuteCmd)
+	viewExecuteCmd.Flags().BoolVar(&viewSkipZeroes, "skip-zeroes", false, "Skip the cells without a value, or with a zero value, or in table format the rows and columns holding nothing but such cells")
+	viewExecuteCmd.Flags().StringVar(&viewValues, "values", "", "Show either 'formatted' or 'raw' values, by default formatted values in table format and raw values otherwise")
+	viewCmd.AddCommand(viewExecuteCmd)
+
+	addDatabaseFlags(viewCreateCmd)
+	addPrivateViewFlag(viewCreateCmd)
+	viewCreateCmd.Flags().StringVar(&viewMDXFile, "mdx", "", "A file containing the MDX query of the view, '-' to read the query from stdin")
+	viewCreateCmd.MarkFlagRequired("mdx")
+	viewCmd.AddCommand(viewCreateCmd)
+
+	addDatabaseFlags(viewDeleteCmd)
+	addPrivateViewFlag(viewDeleteCmd)
+	viewCmd.AddCommand(viewDeleteCmd)
+
+	rootCmd.AddCommand(viewCmd)
+}
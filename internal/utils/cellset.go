@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The default expansion of a cellset returning the hierarchies and the members of the tuples on every axis and the
+// values of the cells
+const CellsetExpand = "$expand=Axes($expand=Hierarchies($select=Name;$expand=Dimension($select=Name)),Tuples($expand=Members($select=Name,UniqueName))),Cells($select=Ordinal,Value,FormattedValue)"
+
+// CellUpdate is the value to be written to the cell identified by its elements, one for every dimension of the cube,
+// each specified by its path as returned by ElementPath
+type CellUpdate struct {
+	Elements []string
+	Value    any
+}
+
+// ElementPath returns the path to the element, as used to bind to the element in requests
+func ElementPath(dimension, hierarchy, element string) string {
+	return "Dimensions" + ODataKey(dimension) + "/Hierarchies" + ODataKey(hierarchy) + "/Elements" + ODataKey(element)
+}
+
+// UpdateCells writes the values to the cells of the cube in a single request
+func UpdateCells(host, instance, database, user, password, cube string, updates []CellUpdate) error {
+	return UpdateSandboxCells(host, instance, database, user, password, cube, "", updates)
+}
+
+// UpdateSandboxCells writes the values to the cells of the cube, in the sandbox or, if no sandbox is specified, in
+// the base data, in a single request
+func UpdateSandboxCells(host, instance, database, user, password, cube, sandbox string, updates []CellUpdate) error {
+	path := DatabaseActionPath(host, instance, database, user, password, "Cubes"+ODataKey(cube), "Update")
+	if sandbox != "" {
+		path += "?!sandbox=" + strings.ReplaceAll(url.QueryEscape(sandbox), "+", "%20")
+	}
+	payload := make([]any, len(updates))
+	for i, update := range updates {
+		payload[i] = map[string]any{
+			"Cells": []any{map[string]any{"Tuple@odata.bind": update.Elements}},
+			"Value": update.Value,
+		}
+	}
+	_, err := DatabaseAPIPost(host, instance, database, user, password, path, payload)
+	return err
+}
+
+// CreateMDXCellset executes the MDX query against the database, returning the cellset expanded as specified. The
+// cellset remains on the server, allowing its cells to be retrieved in pages, until deleted using DeleteCellset.
+func CreateMDXCellset(host, instance, database, user, password, mdx, expand string) (map[string]any, error) {
+	return DatabaseAPIPost(host, instance, database, user, password, "ExecuteMDX?"+expand, map[string]any{"MDX": mdx})
+}
+
+// CreateViewCellset executes the view, identified by its path relative to the database, returning the cellset
+// expanded as specified. The cellset remains on the server until deleted using DeleteCellset.
+func CreateViewCellset(host, instance, database, user, password, path, expand string) (map[string]any, error) {
+	return DatabaseAPIPost(host, instance, database, user, password, DatabaseActionPath(host, instance, database, user, password, path, "Execute")+"?"+expand, map[string]any{})
+}
+
+// ExecuteMDX executes the MDX query against the database, returning the cellset expanded as specified. The cellset
+// is deleted on the server once retrieved.
+func ExecuteMDX(host, instance, database, user, password, mdx, expand string) (map[string]any, error) {
+	if expand == "" {
+		expand = CellsetExpand
+	}
+	cellset, err := CreateMDXCellset(host, instance, database, user, password, mdx, expand)
+	if err != nil {
+		return nil, err
+	}
+	DeleteCellset(host, instance, database, user, password, cellset)
+	return cellset, nil
+}
+
+// ExecuteView executes the view, identified by its path relative to the database, returning the cellset expanded as
+// specified. The cellset is deleted on the server once retrieved.
+func ExecuteView(host, instance, database, user, password, path, expand string) (map[string]any, error) {
+	if expand == "" {
+		expand = CellsetExpand
+	}
+	cellset, err := CreateViewCellset(host, instance, database, user, password, path, expand)
+	if err != nil {
+		return nil, err
+	}
+	DeleteCellset(host, instance, database, user, password, cellset)
+	return cellset, nil
+}
+
+// CellsetCellsPage retrieves a page, of at most top cells starting at ordinal skip, of the cells of the cellset,
+// each cell holding its Ordinal, Value and the Members identifying the cell
+func CellsetCellsPage(host, instance, database, user, password string, cellset map[string]any, skip, top int) ([]map[string]any, error) {
+	id, _ := cellset["ID"].(string)
+	path := fmt.Sprintf("Cellsets%s/Cells?$select=Ordinal,Value&$expand=Members($select=Name,Type)&$skip=%d&$top=%d", ODataKey(id), skip, top)
+	data, err := DatabaseAPIGet(host, instance, database, user, password, path)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := data["value"].([]any)
+	cells := make([]map[string]any, 0, len(list))
+	for _, raw := range list {
+		if cell, ok := raw.(map[string]any); ok {
+			cells = append(cells, cell)
+		}
+	}
+	return cells, nil
+}
+
+// CellsetCardinality returns the number of cells in the cellset, being the product of the cardinality of its axes,
+// requiring the cellset to have been expanded with the Cardinality of its Axes
+func CellsetCardinality(cellset map[string]any) int {
+	axes, _ := cellset["Axes"].([]any)
+	if len(axes) == 0 {
+		return 0
+	}
+	count := 1
+	for _, raw := range axes {
+		axis, _ := raw.(map[string]any)
+		cardinality, _ := axis["Cardinality"].(float64)
+		count *= int(cardinality)
+	}
+	return count
+}
+
+// DeleteCellset disposes of the cellset on the server, failing to do so is reported as a warning only
+func DeleteCellset(host, instance, database, user, password string, cellset map[string]any) {
+	id, ok := cellset["ID"].(string)
+	if !ok || id == "" {
+		return
+	}
+	if err := DatabaseAPIDelete(host, instance, database, user, password, "Cellsets"+ODataKey(id)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cellset '%s' could not be deleted due to: %v\n", id, err)
+	}
+}
+
+// CellsetAxes returns the tuples on each of the axes of the cellset, each tuple represented by its member names
+func CellsetAxes(cellset map[string]any) [][][]string {
+	axes, _ := cellset["Axes"].([]any)
+	result := make([][][]string, len(axes))
+	for i, rawAxis := range axes {
+		axis, _ := rawAxis.(map[string]any)
+		tuples, _ := axis["Tuples"].([]any)
+		result[i] = make([][]string, len(tuples))
+		for j, rawTuple := range tuples {
+			tuple, _ := rawTuple.(map[string]any)
+			members, _ := tuple["Members"].([]any)
+			names := make([]string, len(members))
+			for k, rawMember := range members {
+				member, _ := rawMember.(map[string]any)
+				names[k], _ = member["Name"].(string)
+			}
+			result[i][j] = names
+		}
+	}
+	return result
+}
+
+// CellsetCells returns the cells of the cellset indexed by their ordinal
+func CellsetCells(cellset map[string]any) map[int]map[string]any {
+	cells, _ := cellset["Cells"].([]any)
+	result := make(map[int]map[string]any, len(cells))
+	for i, rawCell := range cells {
+		cell, _ := rawCell.(map[string]any)
+		ordinal := i
+		if f, ok := cell["Ordinal"].(float64); ok {
+			ordinal = int(f)
+		}
+		result[ordinal] = cell
+	}
+	return result
+}
+
+// CellsetHierarchies returns the names of the hierarchies on each of the axes of the cellset
+func CellsetHierarchies(cellset map[string]any) [][]string {
+	axes, _ := cellset["Axes"].([]any)
+	result := make([][]string, len(axes))
+	for i, rawAxis := range axes {
+		axis, _ := rawAxis.(map[string]any)
+		hierarchies, _ := axis["Hierarchies"].([]any)
+		result[i] = make([]string, len(hierarchies))
+		for j, rawHierarchy := range hierarchies {
+			hierarchy, _ := rawHierarchy.(map[string]any)
+			result[i][j], _ = hierarchy["Name"].(string)
+		}
+	}
+	return result
+}
+
+// CellsetDimensions returns the names of the dimensions of the hierarchies on each of the axes of the cellset, the
+// name of the hierarchy being used if the cellset holds no dimension
+func CellsetDimensions(cellset map[string]any) [][]string {
+	axes, _ := cellset["Axes"].([]any)
+	result := make([][]string, len(axes))
+	for i, rawAxis := range axes {
+		axis, _ := rawAxis.(map[string]any)
+		hierarchies, _ := axis["Hierarchies"].([]any)
+		result[i] = make([]string, len(hierarchies))
+		for j, rawHierarchy := range hierarchies {
+			hierarchy, _ := rawHierarchy.(map[string]any)
+			result[i][j], _ = hierarchy["Name"].(string)
+			if dimension, ok := hierarchy["Dimension"].(map[string]any); ok {
+				if name, _ := dimension["Name"].(string); name != "" {
+					result[i][j] = name
+				}
+			}
+		}
+	}
+	return result
+}
+
+// CellsetColumns returns the names of the columns, in records holding the members and the value of a cell, of the
+// hierarchies on each of the axes of the cellset. A column is named after its hierarchy, unless another hierarchy in
+// the cellset has the same name, or the hierarchy is named Value, like the column holding the value of the cell, in
+// which case the column is named after the dimension and the hierarchy, like 'Dimension:Hierarchy'.
+func CellsetColumns(cellset map[string]any) [][]string {
+	result := CellsetHierarchies(cellset)
+	dimensions := CellsetDimensions(cellset)
+	count := map[string]int{NameKey("Value"): 1}
+	for _, axis := range result {
+		for _, name := range axis {
+			count[NameKey(name)]++
+		}
+	}
+	for i := range result {
+		for j, name := range result[i] {
+			if count[NameKey(name)] > 1 {
+				result[i][j] = dimensions[i][j] + ":" + name
+			}
+		}
+	}
+	return result
+}
+
+// IsZeroCell returns true if the cell holds no value, a zero or an empty string
+func IsZeroCell(cell map[string]any) bool {
+	switch val := cell["Value"].(type) {
+	case nil:
+		return true
+	case float64:
+		return val == 0
+	case string:
+		return val == ""
+	}
+	return false
+}
+
+// cellValue returns the, raw or formatted, value of the cell
+func cellValue(cell map[string]any, formatted bool) any {
+	if formatted {
+		return cell["FormattedValue"]
+	}
+	return cell["Value"]
+}
+
+// OutputCellset outputs the cellset, in table format as a grid with the tuples of the first axis as columns and
+// those of the second axis as rows, in the csv and ndjson formats as a record per cell holding its members, in the
+// columns returned by CellsetColumns, and the value, and in json format as is. Cells without a value, or a zero
+// value, are skipped if requested, in a grid only rows and columns holding nothing but such cells are skipped.
+func OutputCellset(cellset map[string]any, skipZeroes, formatted bool) error {
+	switch viper.GetString("output-format") {
+	case "table":
+		return outputCellsetGrid(cellset, skipZeroes, formatted)
+	case "json":
+		return Output(cellset)
+	}
+
+	axes := CellsetAxes(cellset)
+	hierarchies := CellsetColumns(cellset)
+	cells := CellsetCells(cellset)
+
+	// The members of the rows come first, followed by those of the columns and any other axes
+	order := make([]int, 0, len(axes))
+	if len(axes) > 1 {
+		order = append(order, 1, 0)
+		for i := 2; i < len(axes); i++ {
+			order = append(order, i)
+		}
+	} else if len(axes) == 1 {
+		order = append(order, 0)
+	}
+	var columns []string
+	for _, axis := range order {
+		columns = append(columns, hierarchies[axis]...)
+	}
+	columns = append(columns, "Value")
+
+	total := 1
+	for _, tuples := range axes {
+		total *= len(tuples)
+	}
+	records := make([]any, 0, total)
+	for ordinal := 0; ordinal < total; ordinal++ {
+		cell := cells[ordinal]
+		if cell == nil {
+			cell = map[string]any{}
+		}
+		if skipZeroes && IsZeroCell(cell) {
+			continue
+		}
+		record := make(map[string]any, len(columns))
+		index := ordinal
+		for axis, tuples := range axes {
+			tuple := tuples[index%len(tuples)]
+			index /= len(tuples)
+			for i, member := range tuple {
+				if i < len(hierarchies[axis]) {
+					record[hierarchies[axis][i]] = member
+				}
+			}
+		}
+		record["Value"] = cellValue(cell, formatted)
+		records = append(records, record)
+	}
+	return OutputRows(records, columns...)
+}
+
+func outputCellsetGrid(cellset map[string]any, skipZeroes, formatted bool) error {
+	axes := CellsetAxes(cellset)
+	hierarchies := CellsetHierarchies(cellset)
+	cells := CellsetCells(cellset)
+	if len(axes) == 0 {
+		return fmt.Errorf("the cellset has no axes")
+	}
+
+	// Any axes beyond the columns and rows need to hold a single tuple, like the slicer, to be shown as the context
+	var context []string
+	for _, tuples := range axes[min(len(axes), 2):] {
+		if len(tuples) != 1 {
+			return UsageError("only queries with up to 2 axes, not counting the slicer, can be shown in table format")
+		}
+		context = append(context, tuples[0]...)
+	}
+	if len(context) > 0 {
+		fmt.Printf("Context: %s\n\n", strings.Join(context, ", "))
+	}
+
+	columns := axes[0]
+	rows := [][]string{{}}
+	var rowHierarchies []string
+	if len(axes) > 1 {
+		rows = axes[1]
+		rowHierarchies = hierarchies[1]
+		for len(rows) > 0 && len(rowHierarchies) < len(rows[0]) {
+			rowHierarchies = append(rowHierarchies, "")
+		}
+	}
+	cell := func(row, column int) map[string]any {
+		if c, ok := cells[row*len(columns)+column]; ok {
+			return c
+		}
+		return map[string]any{}
+	}
+
+	// Determine the rows and columns to be shown, skipping those without any values if requested
+	var shownRows, shownColumns []int
+	for r := range rows {
+		for c := range columns {
+			if !skipZeroes || !IsZeroCell(cell(r, c)) {
+				shownRows = append(shownRows, r)
+				break
+			}
+		}
+	}
+	for c := range columns {
+		for _, r := range shownRows {
+			if !skipZeroes || !IsZeroCell(cell(r, c)) {
+				shownColumns = append(shownColumns, c)
+				break
+			}
+		}
+	}
+	if len(shownRows) == 0 || len(shownColumns) == 0 {
+		fmt.Println("No data.")
+		return nil
+	}
+
+	headers := append([]string{}, rowHierarchies...)
+	for _, c := range shownColumns {
+		headers = append(headers, strings.Join(columns[c], " / "))
+	}
+	grid := make([][]string, len(shownRows))
+	for i, r := range shownRows {
+		line := append([]string{}, rows[r]...)
+		for _, c := range shownColumns {
+			val := cellValue(cell(r, c), formatted)
+			if val == nil {
+				line = append(line, "")
+			} else {
+				line = append(line, Stringify(val))
+			}
+		}
+		grid[i] = line
+	}
+	OutputGrid(headers, grid)
+	return nil
+}
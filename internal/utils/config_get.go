@@ -22,7 +22,7 @@ func GetHostName(name string) (string, error) {
 
 	// No (active) host specified then return an error
 	if name == "" {
-		return "", fmt.Errorf("no host specified")
+		return "", ConfigError("no host specified")
 	}
 
 	return name, nil
@@ -40,11 +40,11 @@ func GetHostConfiguration(name string) (map[string]any, error) {
 	hosts := viper.GetStringMap("hosts")
 	raw := hosts[name]
 	if raw == nil {
-		return nil, fmt.Errorf("no configuration specified for host '%s'", name)
+		return nil, ConfigError("no configuration specified for host '%s'", name)
 	}
 	host, ok := raw.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("invalid configuration for host '%s', format invalid", name)
+		return nil, ConfigError("invalid configuration for host '%s', format invalid", name)
 	}
 	return host, nil
 }
@@ -58,7 +58,7 @@ func GetUserName(name string) (string, error) {
 
 	// No (active) user specified then return an error
 	if name == "" {
-		return "", fmt.Errorf("no user specified")
+		return "", ConfigError("no user specified")
 	}
 
 	return name, nil
@@ -76,11 +76,11 @@ func GetUserConfiguration(name string) (map[string]any, error) {
 	users := viper.GetStringMap("users")
 	raw := users[name]
 	if raw == nil {
-		return nil, fmt.Errorf("no configuration specified for user '%s'", name)
+		return nil, ConfigError("no configuration specified for user '%s'", name)
 	}
 	user, ok := raw.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("invalid configuration for user '%s', format invalid", name)
+		return nil, ConfigError("invalid configuration for user '%s', format invalid", name)
 	}
 	return user, nil
 }
@@ -93,12 +93,12 @@ func getStringFromHostConfig(name string, config map[string]any, prop_name, prop
 	if raw != nil {
 		cast, ok := raw.(string)
 		if !ok {
-			return "", fmt.Errorf("invalid %s format for host '%s'", prop_name, name)
+			return "", ConfigError("invalid %s format for host '%s'", prop_name, name)
 		}
 		value = cast
 	}
 	if raw == nil || value == "" {
-		return "", fmt.Errorf("invalid configuration, no %s specified for host '%s'", prop_name, name)
+		return "", ConfigError("invalid configuration, no %s specified for host '%s'", prop_name, name)
 	}
 	return value, nil
 }
@@ -141,12 +141,12 @@ func GetInstanceNameFromHostConfig(instance string, config map[string]any) (stri
 		if raw != nil {
 			cast, ok := raw.(string)
 			if !ok {
-				return "", fmt.Errorf("invalid configuration, 'instance' property is not a string")
+				return "", ConfigError("invalid configuration, 'instance' property is not a string")
 			}
 			instance = cast
 		}
 		if raw == nil || instance == "" {
-			return "", fmt.Errorf("no instance specified")
+			return "", ConfigError("no instance specified")
 		}
 	}
 	return instance, nil
@@ -188,6 +188,37 @@ func GetInstanceRootURL(host, instance string) (string, error) {
 	return fmt.Sprintf("%s/%s/api/v1", serviceRootURL, instance), nil
 }
 
+func GetDatabaseNameFromHostConfig(instance, database string, config map[string]any) (string, error) {
+
+	// No database specified then use the active database, which only applies to the active instance
+	if database == "" && (instance == "" || instance == config["instance"]) {
+		raw := config["database"]
+		if raw != nil {
+			cast, ok := raw.(string)
+			if !ok {
+				return "", ConfigError("invalid configuration, 'database' property is not a string")
+			}
+			database = cast
+		}
+	}
+	if database == "" {
+		return "", ConfigError("no database specified")
+	}
+	return database, nil
+}
+
+func GetDatabaseName(host, instance, database string) (string, error) {
+
+	// Lookup the host's configuration
+	config, err := GetHostConfiguration(host)
+	if err != nil {
+		return "", err
+	}
+
+	// No database specified then use active database
+	return GetDatabaseNameFromHostConfig(instance, database, config)
+}
+
 func GetDatabaseRootURL(host, instance, database string) (string, error) {
 
 	// Grab the instance root URL
@@ -196,9 +227,10 @@ func GetDatabaseRootURL(host, instance, database string) (string, error) {
 		return "", err
 	}
 
-	// Make sure a database name is specified
-	if database == "" {
-		return "", fmt.Errorf("no database specified")
+	// No database specified then use active database
+	database, err = GetDatabaseName(host, instance, database)
+	if err != nil {
+		return "", err
 	}
 
 	// Return the database root URL
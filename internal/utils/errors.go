@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// The exit codes used by tm1ctl, note that these are documented in the README and are to remain stable
+const (
+	ExitOK         = 0 // The command completed successfully
+	ExitError      = 1 // The command failed for a reason not covered by any of the other exit codes
+	ExitUsage      = 2 // Invalid command, arguments or flags
+	ExitConfig     = 3 // Invalid or missing configuration, e.g. no host, instance or user specified
+	ExitAuth       = 4 // The request was not authenticated or not authorized (401/403)
+	ExitNotFound   = 5 // The requested, or referenced, resource does not exist (404)
+	ExitConflict   = 6 // The request conflicts with the current state of the resource (409/412)
+	ExitServer     = 7 // The service failed to process the request (5xx)
+	ExitConnection = 8 // The service could not be reached
+	ExitProcess    = 9 // The process executed did not complete successfully
+)
+
+// The names of the exit codes as used in the JSON error object
+var exitCodeNames = map[int]string{
+	ExitError:      "Error",
+	ExitUsage:      "Usage",
+	ExitConfig:     "Configuration",
+	ExitAuth:       "Unauthorized",
+	ExitNotFound:   "NotFound",
+	ExitConflict:   "Conflict",
+	ExitServer:     "ServerError",
+	ExitConnection: "ConnectionFailed",
+	ExitProcess:    "ProcessFailed",
+}
+
+// CLIError is an error with the exit code tm1ctl is to exit with
+type CLIError struct {
+	ExitCode int
+	Err      error
+}
+
+func (e *CLIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CLIError) Unwrap() error {
+	return e.Err
+}
+
+func newCLIError(exitCode int, format string, a ...any) error {
+	return &CLIError{ExitCode: exitCode, Err: fmt.Errorf(format, a...)}
+}
+
+// UsageError returns an error reporting invalid use of a command
+func UsageError(format string, a ...any) error {
+	return newCLIError(ExitUsage, format, a...)
+}
+
+// ConfigError returns an error reporting invalid or missing configuration
+func ConfigError(format string, a ...any) error {
+	return newCLIError(ExitConfig, format, a...)
+}
+
+// NotFoundError returns an error reporting that something does not exist
+func NotFoundError(format string, a ...any) error {
+	return newCLIError(ExitNotFound, format, a...)
+}
+
+// ConflictError returns an error reporting that a request conflicts with the current state of something
+func ConflictError(format string, a ...any) error {
+	return newCLIError(ExitConflict, format, a...)
+}
+
+// ProcessError returns an error reporting that a process did not complete successfully
+func ProcessError(format string, a ...any) error {
+	return newCLIError(ExitProcess, format, a...)
+}
+
+// ConnectionError wraps the error of a request that failed to reach the service
+func ConnectionError(err error) error {
+	return &CLIError{ExitCode: ExitConnection, Err: fmt.Errorf("request failed: %w", err)}
+}
+
+// APIError is the error returned for an error response of the service
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("error response: %s", e.Message)
+}
+
+// newAPIError creates an error from an error response, extracting the message from the OData error object if any
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Message: string(body)}
+	var odataErr struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &odataErr); err == nil && odataErr.Error.Message != "" {
+		apiErr.Code = odataErr.Error.Code
+		apiErr.Message = odataErr.Error.Message
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
+
+// IsNotFound returns true if the error is, or wraps, a 404 Not Found error response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ExitCode returns the exit code to be used for the error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var cliErr *CLIError
+	if errors.As(err, &cliErr) {
+		return cliErr.ExitCode
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ExitAuth
+		case apiErr.StatusCode == http.StatusNotFound:
+			return ExitNotFound
+		case apiErr.StatusCode == http.StatusConflict || apiErr.StatusCode == http.StatusPreconditionFailed:
+			return ExitConflict
+		case apiErr.StatusCode >= 500:
+			return ExitServer
+		}
+	}
+	return ExitError
+}
+
+// ReportError writes the error to stderr, as a JSON error object if any of the JSON output formats is selected,
+// and returns the exit code to be used
+func ReportError(err error) int {
+	exitCode := ExitCode(err)
+	if format := viper.GetString("output-format"); format != "json" && format != "ndjson" {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if exitCode == ExitUsage {
+			fmt.Fprintln(os.Stderr, "Run 'tm1ctl --help' or add --help to the command for usage.")
+		}
+		return exitCode
+	}
+
+	details := map[string]any{
+		"code":     exitCodeNames[exitCode],
+		"message":  err.Error(),
+		"exitCode": exitCode,
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		details["statusCode"] = apiErr.StatusCode
+		if apiErr.Code != "" {
+			details["serviceCode"] = apiErr.Code
+		}
+	}
+	enc := json.NewEncoder(os.Stderr)
+	enc.Encode(map[string]any{"error": details})
+	return exitCode
+}
@@ -0,0 +1,665 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The supported hierarchy file formats
+const (
+	HierarchyFormatParentChild = "parent-child"
+	HierarchyFormatLevels      = "levels"
+	HierarchyFormatJSON        = "json"
+)
+
+// The types of changes needed to turn one hierarchy into another
+const (
+	HierarchyAddElement      = "AddElement"
+	HierarchyChangeType      = "ChangeType"
+	HierarchyAddComponent    = "AddComponent"
+	HierarchyMoveComponent   = "MoveComponent"
+	HierarchyUpdateWeight    = "UpdateWeight"
+	HierarchyRemoveComponent = "RemoveComponent"
+	HierarchyDeleteElement   = "DeleteElement"
+	HierarchySetAttribute    = "SetAttribute"
+)
+
+// HierarchyElement is an element of a hierarchy, with its attribute values by attribute name
+type HierarchyElement struct {
+	Name       string
+	Type       string
+	Attributes map[string]any
+}
+
+// HierarchyEdge is the relationship between a consolidated element and one of its components
+type HierarchyEdge struct {
+	Parent    string
+	Component string
+	Weight    float64
+}
+
+// Hierarchy is the structure, and the attribute values, of a hierarchy as retrieved from the service or read
+// from, or written to, a hierarchy file
+type Hierarchy struct {
+	Elements   []*HierarchyElement
+	Edges      []*HierarchyEdge
+	Attributes []string
+	elements   map[string]*HierarchyElement
+	edges      map[string]*HierarchyEdge
+}
+
+// HierarchyChange is a change needed to turn one hierarchy into another
+type HierarchyChange struct {
+	Action    string
+	Element   string
+	Parent    string
+	From      string
+	Type      string
+	Weight    float64
+	Attribute string
+	Value     any
+}
+
+// hierarchyNode is an element, with its components, as represented in a JSON hierarchy file
+type hierarchyNode struct {
+	Name       string           `json:"Name"`
+	Type       string           `json:"Type,omitempty"`
+	Weight     *float64         `json:"Weight,omitempty"`
+	Attributes map[string]any   `json:"Attributes,omitempty"`
+	Children   []*hierarchyNode `json:"Children,omitempty"`
+}
+
+// The element types, by their lower case name or abbreviation
+var elementTypes = map[string]string{
+	"numeric":      "Numeric",
+	"n":            "Numeric",
+	"string":       "String",
+	"s":            "String",
+	"consolidated": "Consolidated",
+	"c":            "Consolidated",
+}
+
+// ParseElementType returns the element type, Numeric, String or Consolidated, for its name or abbreviation
+func ParseElementType(name string) (string, error) {
+	elementType, ok := elementTypes[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", UsageError("invalid element type '%s', supported types are: Numeric, String and Consolidated", name)
+	}
+	return elementType, nil
+}
+
+// NameKey returns the key identifying an object by its name, noting that names in TM1 are case and space insensitive
+func NameKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+func edgeKey(parent, component string) string {
+	return NameKey(parent) + "\x00" + NameKey(component)
+}
+
+// NewHierarchy returns an empty hierarchy holding values for the specified attributes
+func NewHierarchy(attributes []string) *Hierarchy {
+	return &Hierarchy{
+		Attributes: attributes,
+		elements:   make(map[string]*HierarchyElement),
+		edges:      make(map[string]*HierarchyEdge),
+	}
+}
+
+// Element returns the element with the specified name, nil if it doesn't exist
+func (h *Hierarchy) Element(name string) *HierarchyElement {
+	return h.elements[NameKey(name)]
+}
+
+// AddElement adds the element, if it doesn't exist yet, and sets its type, if specified
+func (h *Hierarchy) AddElement(name, elementType string) *HierarchyElement {
+	element := h.Element(name)
+	if element == nil {
+		element = &HierarchyElement{Name: name, Attributes: make(map[string]any)}
+		h.elements[NameKey(name)] = element
+		h.Elements = append(h.Elements, element)
+	}
+	if elementType != "" {
+		element.Type = elementType
+	}
+	return element
+}
+
+// Edge returns the edge between the parent and the component, nil if the component isn't part of the parent
+func (h *Hierarchy) Edge(parent, component string) *HierarchyEdge {
+	return h.edges[edgeKey(parent, component)]
+}
+
+// AddEdge adds the component to the parent, adding either element if it doesn't exist yet, or, if the component
+// is already part of the parent, updates its weight
+func (h *Hierarchy) AddEdge(parent, component string, weight float64) {
+	h.AddElement(parent, "")
+	h.AddElement(component, "")
+	if edge := h.Edge(parent, component); edge != nil {
+		edge.Weight = weight
+		return
+	}
+	edge := &HierarchyEdge{Parent: h.Element(parent).Name, Component: h.Element(component).Name, Weight: weight}
+	h.edges[edgeKey(parent, component)] = edge
+	h.Edges = append(h.Edges, edge)
+}
+
+// Components returns the edges to the components of the element, in order
+func (h *Hierarchy) Components(name string) []*HierarchyEdge {
+	var edges []*HierarchyEdge
+	for _, edge := range h.Edges {
+		if NameKey(edge.Parent) == NameKey(name) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Parents returns the edges to the parents of the element
+func (h *Hierarchy) Parents(name string) []*HierarchyEdge {
+	var edges []*HierarchyEdge
+	for _, edge := range h.Edges {
+		if NameKey(edge.Component) == NameKey(name) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// ResolveTypes makes elements with components consolidated and elements without a type numeric
+func (h *Hierarchy) ResolveTypes() {
+	for _, edge := range h.Edges {
+		h.Element(edge.Parent).Type = "Consolidated"
+	}
+	for _, element := range h.Elements {
+		if element.Type == "" {
+			element.Type = "Numeric"
+		}
+	}
+}
+
+// HierarchyFormat returns the hierarchy file format, as specified or, if not, based on the extension of the file
+func HierarchyFormat(format, file string) (string, error) {
+	switch format {
+	case HierarchyFormatParentChild, HierarchyFormatLevels, HierarchyFormatJSON:
+		return format, nil
+	case "":
+		if strings.EqualFold(filepath.Ext(file), ".json") {
+			return HierarchyFormatJSON, nil
+		}
+		return HierarchyFormatParentChild, nil
+	}
+	return "", UsageError("invalid format '%s', supported formats are: %s, %s and %s", format, HierarchyFormatParentChild, HierarchyFormatLevels, HierarchyFormatJSON)
+}
+
+// ReadHierarchy reads a hierarchy file in the specified format
+func ReadHierarchy(r io.Reader, format string) (*Hierarchy, error) {
+	var h *Hierarchy
+	var err error
+	switch format {
+	case HierarchyFormatJSON:
+		h, err = readHierarchyJSON(r)
+	default:
+		h, err = readHierarchyCSV(r, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.ResolveTypes()
+	return h, nil
+}
+
+// parseWeight returns the weight in a cell, 1 if the cell is empty
+func parseWeight(cell string, line int) (float64, error) {
+	if cell == "" {
+		return 1, nil
+	}
+	weight, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		return 0, UsageError("line %d: invalid weight '%s'", line, cell)
+	}
+	return weight, nil
+}
+
+// readHierarchyCSV reads a parent-child file, with a row for every element and parent, or a level-column file,
+// with a row for every path from a top level element to a leaf. Both require a header row, the columns not
+// identifying the structure, type or weight holding the values of the attribute with the column's name.
+func readHierarchyCSV(r io.Reader, format string) (*Hierarchy, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, UsageError("unable to read %s file: %v", format, err)
+	}
+	if len(records) == 0 {
+		return nil, UsageError("the %s file has no header row", format)
+	}
+
+	parentCol, elementCol, typeCol, weightCol := -1, -1, -1, -1
+	var levelCols, attributeCols []int
+	var attributes []string
+	for i, name := range records[0] {
+		name = strings.TrimSpace(name)
+		lower := strings.ToLower(name)
+		switch {
+		case lower == "type":
+			typeCol = i
+		case lower == "weight":
+			weightCol = i
+		case format == HierarchyFormatParentChild && lower == "parent":
+			parentCol = i
+		case format == HierarchyFormatParentChild && (lower == "element" || lower == "child"):
+			elementCol = i
+		case format == HierarchyFormatLevels && strings.HasPrefix(lower, "level"):
+			levelCols = append(levelCols, i)
+		default:
+			attributeCols = append(attributeCols, i)
+			attributes = append(attributes, name)
+		}
+	}
+	if format == HierarchyFormatParentChild && elementCol == -1 {
+		return nil, UsageError("the parent-child file requires an 'Element' column")
+	}
+	if format == HierarchyFormatLevels && len(levelCols) == 0 {
+		return nil, UsageError("the levels file requires one or more 'Level' columns")
+	}
+
+	h := NewHierarchy(attributes)
+	for n, record := range records[1:] {
+		line := n + 2
+		cell := func(i int) string {
+			if i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		// Determine the element, the leaf in a levels file, and its parent, if any, that the row describes
+		var name, parent string
+		if format == HierarchyFormatParentChild {
+			name, parent = cell(elementCol), cell(parentCol)
+		} else {
+			var path []string
+			for _, col := range levelCols {
+				if val := cell(col); val != "" {
+					path = append(path, val)
+				}
+			}
+			for i := 1; i < len(path)-1; i++ {
+				if h.Edge(path[i-1], path[i]) == nil {
+					h.AddEdge(path[i-1], path[i], 1)
+				}
+			}
+			if len(path) > 0 {
+				name = path[len(path)-1]
+			}
+			if len(path) > 1 {
+				parent = path[len(path)-2]
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		elementType := ""
+		if val := cell(typeCol); val != "" {
+			if elementType, err = ParseElementType(val); err != nil {
+				return nil, UsageError("line %d: %v", line, err)
+			}
+		}
+		element := h.AddElement(name, elementType)
+		if parent != "" {
+			weight, err := parseWeight(cell(weightCol), line)
+			if err != nil {
+				return nil, err
+			}
+			h.AddEdge(parent, name, weight)
+		}
+		for i, col := range attributeCols {
+			if val := cell(col); val != "" {
+				element.Attributes[attributes[i]] = val
+			}
+		}
+	}
+	return h, nil
+}
+
+// ReadAttributeValues reads a CSV file holding the attribute values of elements, requiring a header row with an
+// Element column, all other columns holding the values of the attribute with the column's name. Empty values are
+// left out, the elements being returned, without any structure, in a hierarchy.
+func ReadAttributeValues(r io.Reader) (*Hierarchy, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, UsageError("unable to read attribute values file: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, UsageError("the attribute values file has no header row")
+	}
+
+	elementCol := -1
+	var attributeCols []int
+	var attributes []string
+	for i, name := range records[0] {
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, "element") && elementCol == -1 {
+			elementCol = i
+			continue
+		}
+		attributeCols = append(attributeCols, i)
+		attributes = append(attributes, name)
+	}
+	if elementCol == -1 {
+		return nil, UsageError("the attribute values file requires an 'Element' column")
+	}
+
+	h := NewHierarchy(attributes)
+	for _, record := range records[1:] {
+		cell := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		name := cell(elementCol)
+		if name == "" {
+			continue
+		}
+		element := h.AddElement(name, "")
+		for i, col := range attributeCols {
+			if val := cell(col); val != "" {
+				element.Attributes[attributes[i]] = val
+			}
+		}
+	}
+	return h, nil
+}
+
+// readHierarchyJSON reads a JSON file holding the top level elements with, recursively, their components
+func readHierarchyJSON(r io.Reader) (*Hierarchy, error) {
+	var roots []*hierarchyNode
+	if err := json.NewDecoder(r).Decode(&roots); err != nil {
+		return nil, UsageError("unable to read json file: %v", err)
+	}
+
+	h := NewHierarchy(nil)
+	attributes := make(map[string]bool)
+	var add func(node *hierarchyNode, parent string) error
+	add = func(node *hierarchyNode, parent string) error {
+		if node.Name == "" {
+			return UsageError("element without a name found in json file")
+		}
+		elementType := ""
+		if node.Type != "" {
+			var err error
+			if elementType, err = ParseElementType(node.Type); err != nil {
+				return err
+			}
+		}
+		element := h.AddElement(node.Name, elementType)
+		if parent != "" {
+			weight := 1.0
+			if node.Weight != nil {
+				weight = *node.Weight
+			}
+			h.AddEdge(parent, node.Name, weight)
+		}
+		for name, val := range node.Attributes {
+			element.Attributes[name] = val
+			attributes[name] = true
+		}
+		for _, child := range node.Children {
+			if err := add(child, node.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := add(root, ""); err != nil {
+			return nil, err
+		}
+	}
+	for name := range attributes {
+		h.Attributes = append(h.Attributes, name)
+	}
+	sort.Strings(h.Attributes)
+	return h, nil
+}
+
+// WriteHierarchy writes the hierarchy to a hierarchy file in the specified format
+func WriteHierarchy(w io.Writer, h *Hierarchy, format string) error {
+	switch format {
+	case HierarchyFormatJSON:
+		return writeHierarchyJSON(w, h)
+	case HierarchyFormatLevels:
+		return writeHierarchyLevels(w, h)
+	default:
+		return writeHierarchyParentChild(w, h)
+	}
+}
+
+// attributeCells returns the values of the attributes of the element as cells
+func (h *Hierarchy) attributeCells(element *HierarchyElement) []string {
+	cells := make([]string, len(h.Attributes))
+	for i, name := range h.Attributes {
+		if val, ok := element.Attributes[name]; ok && val != nil {
+			cells[i] = Stringify(val)
+		}
+	}
+	return cells
+}
+
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64)
+}
+
+func writeHierarchyParentChild(w io.Writer, h *Hierarchy) error {
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"Parent", "Element", "Type", "Weight"}, h.Attributes...))
+	for _, element := range h.Elements {
+		attributes := h.attributeCells(element)
+		parents := h.Parents(element.Name)
+		if len(parents) == 0 {
+			writer.Write(append([]string{"", element.Name, element.Type, ""}, attributes...))
+		}
+		for _, edge := range parents {
+			writer.Write(append([]string{edge.Parent, element.Name, element.Type, formatWeight(edge.Weight)}, attributes...))
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// roots returns the elements that aren't a component of any other element, in order
+func (h *Hierarchy) roots() []*HierarchyElement {
+	components := make(map[string]bool)
+	for _, edge := range h.Edges {
+		components[NameKey(edge.Component)] = true
+	}
+	var roots []*HierarchyElement
+	for _, element := range h.Elements {
+		if !components[NameKey(element.Name)] {
+			roots = append(roots, element)
+		}
+	}
+	return roots
+}
+
+// writeHierarchyLevels writes a row for every path from a top level element to a leaf, the type, weight and
+// attribute values being the ones of the leaf
+func writeHierarchyLevels(w io.Writer, h *Hierarchy) error {
+	type row struct {
+		path   []string
+		weight string
+		leaf   *HierarchyElement
+	}
+	var rows []row
+	depth := 0
+	var walk func(element *HierarchyElement, path []string, weight string)
+	walk = func(element *HierarchyElement, path []string, weight string) {
+		path = append(path[:len(path):len(path)], element.Name)
+		components := h.Components(element.Name)
+		if len(components) == 0 {
+			rows = append(rows, row{path, weight, element})
+			if len(path) > depth {
+				depth = len(path)
+			}
+			return
+		}
+		for _, edge := range components {
+			walk(h.Element(edge.Component), path, formatWeight(edge.Weight))
+		}
+	}
+	for _, root := range h.roots() {
+		walk(root, nil, "")
+	}
+
+	writer := csv.NewWriter(w)
+	header := make([]string, 0, depth+2+len(h.Attributes))
+	for i := 1; i <= depth; i++ {
+		header = append(header, "Level"+strconv.Itoa(i))
+	}
+	writer.Write(append(append(header, "Type", "Weight"), h.Attributes...))
+	for _, r := range rows {
+		record := make([]string, depth, depth+2+len(h.Attributes))
+		copy(record, r.path)
+		writer.Write(append(append(record, r.leaf.Type, r.weight), h.attributeCells(r.leaf)...))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeHierarchyJSON(w io.Writer, h *Hierarchy) error {
+	var node func(element *HierarchyElement, weight *float64) *hierarchyNode
+	node = func(element *HierarchyElement, weight *float64) *hierarchyNode {
+		n := &hierarchyNode{Name: element.Name, Type: element.Type, Weight: weight}
+		for _, name := range h.Attributes {
+			if val, ok := element.Attributes[name]; ok && val != nil && val != "" {
+				if n.Attributes == nil {
+					n.Attributes = make(map[string]any)
+				}
+				n.Attributes[name] = val
+			}
+		}
+		for _, edge := range h.Components(element.Name) {
+			weight := edge.Weight
+			n.Children = append(n.Children, node(h.Element(edge.Component), &weight))
+		}
+		return n
+	}
+	roots := []*hierarchyNode{}
+	for _, root := range h.roots() {
+		roots = append(roots, node(root, nil))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(roots)
+}
+
+// DiffHierarchy returns the changes needed to turn the current hierarchy into the target hierarchy, in the order
+// in which they are to be applied. Only the values of the attributes of the target hierarchy are compared.
+func DiffHierarchy(current, target *Hierarchy) []HierarchyChange {
+	var added, consolidated, edges, removed, typed, deleted, attributes []HierarchyChange
+
+	for _, element := range target.Elements {
+		existing := current.Element(element.Name)
+		switch {
+		case existing == nil:
+			added = append(added, HierarchyChange{Action: HierarchyAddElement, Element: element.Name, Type: element.Type})
+		case existing.Type != element.Type && element.Type == "Consolidated":
+			consolidated = append(consolidated, HierarchyChange{Action: HierarchyChangeType, Element: element.Name, Type: element.Type})
+		case existing.Type != element.Type:
+			typed = append(typed, HierarchyChange{Action: HierarchyChangeType, Element: element.Name, Type: element.Type})
+		}
+		for _, name := range target.Attributes {
+			val, ok := element.Attributes[name]
+			if !ok {
+				continue
+			}
+			if existing != nil && Stringify(existing.Attributes[name]) == Stringify(val) {
+				continue
+			}
+			attributes = append(attributes, HierarchyChange{Action: HierarchySetAttribute, Element: element.Name, Attribute: name, Value: val})
+		}
+	}
+
+	// Edges added and removed, ignoring those of deleted elements, with an element removed from one parent and
+	// added to another being reported as a move
+	addedEdges := make(map[string][]*HierarchyEdge)
+	removedEdges := make(map[string][]*HierarchyEdge)
+	for _, edge := range target.Edges {
+		existing := current.Edge(edge.Parent, edge.Component)
+		switch {
+		case existing == nil:
+			addedEdges[NameKey(edge.Component)] = append(addedEdges[NameKey(edge.Component)], edge)
+		case existing.Weight != edge.Weight:
+			edges = append(edges, HierarchyChange{Action: HierarchyUpdateWeight, Element: edge.Component, Parent: edge.Parent, Weight: edge.Weight})
+		}
+	}
+	for _, edge := range current.Edges {
+		if target.Edge(edge.Parent, edge.Component) == nil && target.Element(edge.Parent) != nil && target.Element(edge.Component) != nil {
+			removedEdges[NameKey(edge.Component)] = append(removedEdges[NameKey(edge.Component)], edge)
+		}
+	}
+	for _, edge := range target.Edges {
+		key := NameKey(edge.Component)
+		if len(addedEdges[key]) == 1 && len(removedEdges[key]) == 1 && addedEdges[key][0] == edge {
+			edges = append(edges, HierarchyChange{Action: HierarchyMoveComponent, Element: edge.Component, Parent: edge.Parent, From: removedEdges[key][0].Parent, Weight: edge.Weight})
+			delete(removedEdges, key)
+			continue
+		}
+		for _, added := range addedEdges[key] {
+			if added == edge {
+				edges = append(edges, HierarchyChange{Action: HierarchyAddComponent, Element: edge.Component, Parent: edge.Parent, Weight: edge.Weight})
+			}
+		}
+	}
+	for _, edge := range current.Edges {
+		for _, r := range removedEdges[NameKey(edge.Component)] {
+			if r == edge {
+				removed = append(removed, HierarchyChange{Action: HierarchyRemoveComponent, Element: edge.Component, Parent: edge.Parent})
+			}
+		}
+	}
+
+	for _, element := range current.Elements {
+		if target.Element(element.Name) == nil {
+			deleted = append(deleted, HierarchyChange{Action: HierarchyDeleteElement, Element: element.Name})
+		}
+	}
+
+	var changes []HierarchyChange
+	for _, list := range [][]HierarchyChange{added, consolidated, edges, removed, typed, deleted, attributes} {
+		changes = append(changes, list...)
+	}
+	return changes
+}
+
+// Row returns the change as an object holding the properties relevant to the action
+func (c HierarchyChange) Row() map[string]any {
+	row := map[string]any{"Action": c.Action, "Element": c.Element}
+	switch c.Action {
+	case HierarchyAddElement, HierarchyChangeType:
+		row["Type"] = c.Type
+	case HierarchyAddComponent, HierarchyUpdateWeight:
+		row["Parent"] = c.Parent
+		row["Weight"] = c.Weight
+	case HierarchyMoveComponent:
+		row["Parent"] = c.Parent
+		row["From"] = c.From
+		row["Weight"] = c.Weight
+	case HierarchyRemoveComponent:
+		row["Parent"] = c.Parent
+	case HierarchySetAttribute:
+		row["Attribute"] = c.Attribute
+		row["Value"] = c.Value
+	}
+	return row
+}
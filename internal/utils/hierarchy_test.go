@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testHierarchy returns a hierarchy with a Total consolidating North, with Amsterdam and Berlin, and South, with Rome
+func testHierarchy() *Hierarchy {
+	h := NewHierarchy([]string{"Code"})
+	h.AddEdge("Total", "North", 1)
+	h.AddEdge("North", "Amsterdam", 1)
+	h.AddEdge("North", "Berlin", 1)
+	h.AddEdge("Total", "South", 1)
+	h.AddEdge("South", "Rome", 1)
+	h.Element("Amsterdam").Attributes["Code"] = "AMS"
+	h.Element("Berlin").Attributes["Code"] = "BER"
+	h.Element("Rome").Attributes["Code"] = "ROM"
+	h.ResolveTypes()
+	return h
+}
+
+func TestReadHierarchyCSVParentChild(t *testing.T) {
+	input := `Parent,Element,Type,Weight,Code
+,Total,,,
+Total,North,,,
+North,Amsterdam,N,,AMS
+North,berlin,,-1,BER
+Total,Text,S,,
+`
+	h, err := ReadHierarchy(strings.NewReader(input), HierarchyFormatParentChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, element := range h.Elements {
+		names = append(names, element.Name+":"+element.Type)
+	}
+	want := []string{"Total:Consolidated", "North:Consolidated", "Amsterdam:Numeric", "berlin:Numeric", "Text:String"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("elements = %v, want %v", names, want)
+	}
+	if !reflect.DeepEqual(h.Attributes, []string{"Code"}) {
+		t.Errorf("attributes = %v, want [Code]", h.Attributes)
+	}
+	if edge := h.Edge("NORTH", "Berlin"); edge == nil || edge.Weight != -1 {
+		t.Errorf("edge North/Berlin = %+v, want weight -1", edge)
+	}
+	if got := h.Element("Amsterdam").Attributes["Code"]; got != "AMS" {
+		t.Errorf("Code of Amsterdam = %v, want AMS", got)
+	}
+	if _, ok := h.Element("North").Attributes["Code"]; ok {
+		t.Errorf("empty Code of North should not be set")
+	}
+}
+
+func TestReadHierarchyCSVLevels(t *testing.T) {
+	input := `Level1,Level2,Level3,Weight,Code
+Total,North,Amsterdam,,AMS
+Total,North,Berlin,2,BER
+Total,South,,,
+`
+	h, err := ReadHierarchy(strings.NewReader(input), HierarchyFormatLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Elements) != 5 {
+		t.Fatalf("got %d elements, want 5", len(h.Elements))
+	}
+	if len(h.Edges) != 4 {
+		t.Fatalf("got %d edges, want 4", len(h.Edges))
+	}
+	if edge := h.Edge("North", "Berlin"); edge == nil || edge.Weight != 2 {
+		t.Errorf("edge North/Berlin = %+v, want weight 2", edge)
+	}
+	if edge := h.Edge("Total", "North"); edge == nil || edge.Weight != 1 {
+		t.Errorf("edge Total/North = %+v, want weight 1", edge)
+	}
+	if got := h.Element("South").Type; got != "Numeric" {
+		t.Errorf("type of South = %s, want Numeric", got)
+	}
+	if got := h.Element("Berlin").Attributes["Code"]; got != "BER" {
+		t.Errorf("Code of Berlin = %v, want BER", got)
+	}
+}
+
+func TestReadHierarchyCSVErrors(t *testing.T) {
+	tests := []struct {
+		name, format, input string
+	}{
+		{"empty", HierarchyFormatParentChild, ""},
+		{"no element column", HierarchyFormatParentChild, "Parent,Name\nA,B\n"},
+		{"no level columns", HierarchyFormatLevels, "Element,Type\nA,N\n"},
+		{"invalid type", HierarchyFormatParentChild, "Element,Type\nA,X\n"},
+		{"invalid weight", HierarchyFormatParentChild, "Parent,Element,Weight\nA,B,heavy\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := readHierarchyCSV(strings.NewReader(test.input), test.format); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadAttributeValues(t *testing.T) {
+	input := `Element,Parent,Type,Weight,Code
+Amsterdam,Holland,City,,AMS
+Berlin,,,1,
+`
+	h, err := ReadAttributeValues(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Parent", "Type", "Weight", "Code"}; !reflect.DeepEqual(h.Attributes, want) {
+		t.Errorf("attributes = %v, want %v", h.Attributes, want)
+	}
+	if len(h.Elements) != 2 || len(h.Edges) != 0 {
+		t.Fatalf("got %d elements and %d edges, want 2 elements and no edges", len(h.Elements), len(h.Edges))
+	}
+	want := map[string]any{"Parent": "Holland", "Type": "City", "Code": "AMS"}
+	if got := h.Element("Amsterdam").Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes of Amsterdam = %v, want %v", got, want)
+	}
+	if got := h.Element("Berlin").Attributes; !reflect.DeepEqual(got, map[string]any{"Weight": "1"}) {
+		t.Errorf("attributes of Berlin = %v, want Weight 1", got)
+	}
+
+	if _, err := ReadAttributeValues(strings.NewReader("Name,Code\nA,B\n")); err == nil {
+		t.Error("expected an error reading a file without an Element column")
+	}
+}
+
+func TestWriteHierarchyLevels(t *testing.T) {
+	h := testHierarchy()
+	h.AddElement("Other", "Numeric")
+	h.Edge("North", "Berlin").Weight = 0.5
+
+	var buf bytes.Buffer
+	if err := writeHierarchyLevels(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	want := `Level1,Level2,Level3,Type,Weight,Code
+Total,North,Amsterdam,Numeric,1,AMS
+Total,North,Berlin,Numeric,0.5,BER
+Total,South,Rome,Numeric,1,ROM
+Other,,,Numeric,,
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteHierarchyLevelsRoundTrip(t *testing.T) {
+	h := testHierarchy()
+	var buf bytes.Buffer
+	if err := WriteHierarchy(&buf, h, HierarchyFormatLevels); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadHierarchy(&buf, HierarchyFormatLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := DiffHierarchy(h, read); len(changes) != 0 {
+		t.Errorf("expected no changes after a round trip, got %+v", changes)
+	}
+}
+
+// actions returns the changes as 'Action Element' strings
+func actions(changes []HierarchyChange) []string {
+	var result []string
+	for _, change := range changes {
+		result = append(result, change.Action+" "+change.Element)
+	}
+	return result
+}
+
+func TestDiffHierarchy(t *testing.T) {
+	current := testHierarchy()
+
+	target := NewHierarchy([]string{"Code"})
+	target.AddEdge("Total", "North", 1)
+	target.AddEdge("North", "Amsterdam", 2)
+	target.AddEdge("Total", "South", 1)
+	target.AddEdge("South", "Berlin", 1)
+	target.AddEdge("South", "Madrid", 1)
+	target.Element("Amsterdam").Attributes["Code"] = "AMS"
+	target.Element("Berlin").Attributes["Code"] = "B"
+	target.ResolveTypes()
+
+	changes := DiffHierarchy(current, target)
+	want := []string{
+		"AddElement Madrid",
+		"UpdateWeight Amsterdam",
+		"MoveComponent Berlin",
+		"AddComponent Madrid",
+		"DeleteElement Rome",
+		"SetAttribute Berlin",
+	}
+	if got := actions(changes); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	move := changes[2]
+	if move.Parent != "South" || move.From != "North" {
+		t.Errorf("move = %+v, want from North to South", move)
+	}
+	if changes[1].Weight != 2 {
+		t.Errorf("weight = %v, want 2", changes[1].Weight)
+	}
+	if changes[5].Attribute != "Code" || changes[5].Value != "B" {
+		t.Errorf("attribute change = %+v, want Code B", changes[5])
+	}
+}
+
+func TestDiffHierarchyTypes(t *testing.T) {
+	current := testHierarchy()
+
+	// Rome becomes a consolidation, of the new Vatican, and Berlin a string element
+	target := testHierarchy()
+	target.AddEdge("Rome", "Vatican", 1)
+	target.Element("Berlin").Type = "String"
+	target.ResolveTypes()
+
+	want := []string{
+		"AddElement Vatican",
+		"ChangeType Rome",
+		"AddComponent Vatican",
+		"ChangeType Berlin",
+	}
+	if got := actions(DiffHierarchy(current, target)); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestDiffHierarchyIgnoresCaseAndMissingAttributes(t *testing.T) {
+	current := testHierarchy()
+
+	target := NewHierarchy([]string{"Code"})
+	target.AddEdge("TOTAL", "north", 1)
+	target.AddEdge("north", "amsterdam", 1)
+	target.AddEdge("north", "berlin", 1)
+	target.AddEdge("total", "south", 1)
+	target.AddEdge("south", "rome", 1)
+	target.ResolveTypes()
+
+	if changes := DiffHierarchy(current, target); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", actions(changes))
+	}
+}
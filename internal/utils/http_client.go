@@ -17,6 +17,10 @@ import (
 var (
 	client     *http.Client
 	clientOnce sync.Once
+
+	// The authorization used per service, identified by its $metadata URL, so we can fetch its metadata later
+	authorizations   = make(map[string]string)
+	authorizationsMu sync.Mutex
 )
 
 func getHttpClient() *http.Client {
@@ -27,6 +31,65 @@ func getHttpClient() *http.Client {
 	return client
 }
 
+func rememberAuthorization(metadataURL, authorization string) {
+	authorizationsMu.Lock()
+	defer authorizationsMu.Unlock()
+	authorizations[metadataURL] = authorization
+}
+
+func lookupAuthorization(metadataURL string) (string, bool) {
+	authorizationsMu.Lock()
+	defer authorizationsMu.Unlock()
+	authorization, ok := authorizations[metadataURL]
+	return authorization, ok
+}
+
+// resolveContext makes the @odata.context in the response absolute and remembers the authorization used for
+// the service it references so the output can lookup the metadata describing the response later. The authorization
+// is only remembered if the request was sent to that service, credentials are never sent anywhere else.
+func resolveContext(url, authorization string, result map[string]any) {
+	raw, ok := result["@odata.context"].(string)
+	if !ok || raw == "" {
+		return
+	}
+	contextURL := resolveContextURL(url, raw)
+	result["@odata.context"] = contextURL
+	metadataURL := metadataURLFromContext(contextURL)
+	if withinService(url, metadataURL) {
+		rememberAuthorization(metadataURL, authorization)
+	}
+}
+
+func internalGetRaw(url, authorization, accept string) ([]byte, error) {
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GET request: %w", err)
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := getHttpClient().Do(req)
+	if err != nil {
+		return nil, ConnectionError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
 func internalGet(url, authorization string) (map[string]any, error) {
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -41,13 +104,13 @@ func internalGet(url, authorization string) (map[string]any, error) {
 
 	resp, err := getHttpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, ConnectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error response: %s", body)
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var result map[string]any
@@ -55,11 +118,12 @@ func internalGet(url, authorization string) (map[string]any, error) {
 	if err := decoder.Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode JSON: %w", err)
 	}
+	resolveContext(url, authorization, result)
 
 	return result, nil
 }
 
-func internalPost(url, authorization string, payload map[string]any) (map[string]any, error) {
+func internalPost(url, authorization string, payload any) (map[string]any, error) {
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -79,13 +143,13 @@ func internalPost(url, authorization string, payload map[string]any) (map[string
 
 	resp, err := getHttpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, ConnectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error response: %s", body)
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var result map[string]any
@@ -94,6 +158,49 @@ func internalPost(url, authorization string, payload map[string]any) (map[string
 		if err := decoder.Decode(&result); err != nil {
 			return nil, fmt.Errorf("failed to decode JSON: %w", err)
 		}
+		resolveContext(url, authorization, result)
+	}
+	return result, nil
+}
+
+func internalPatch(url, authorization string, payload map[string]any) (map[string]any, error) {
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PATCH request: %w", err)
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := getHttpClient().Do(req)
+	if err != nil {
+		return nil, ConnectionError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var result map[string]any
+	if resp.StatusCode != http.StatusNoContent {
+		decoder := json.NewDecoder(resp.Body)
+		if err := decoder.Decode(&result); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		if result != nil {
+			resolveContext(url, authorization, result)
+		}
 	}
 	return result, nil
 }
@@ -118,13 +225,13 @@ func internalPutFile(url, authorization, file string) error {
 
 	resp, err := getHttpClient().Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return ConnectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("error response: %s", body)
+		return newAPIError(resp.StatusCode, body)
 	}
 
 	return nil
@@ -144,13 +251,13 @@ func internalDelete(url, authorization string) error {
 
 	resp, err := getHttpClient().Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return ConnectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("error response: %s", body)
+		return newAPIError(resp.StatusCode, body)
 	}
 
 	return nil
@@ -197,7 +304,7 @@ func buildUserAuthorizationHeader(user, password string) (string, error) {
 		// Use the data for the specified user, using the password if one provided
 		userMap, ok := raw.(map[string]any)
 		if !ok {
-			return "", fmt.Errorf("invalid configuration for user '%s', format invalid", user)
+			return "", ConfigError("invalid configuration for user '%s', format invalid", user)
 		}
 		// Name is not needed if it's the same as the user we set it on/for
 		raw = userMap["name"]
@@ -290,6 +397,30 @@ func ManageAPIDelete(host, path string) error {
 	return internalDelete(url, authorization)
 }
 
+func ManageAPIMetadata(host string, refresh bool) (*Metadata, error) {
+
+	// Lookup the host's configuration
+	config, err := GetHostConfiguration(host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grab the service root url
+	serviceRootURL, err := GetServiceRootURLFromHostConfig(host, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build URL and authorization header (root)
+	url := fmt.Sprintf("%s/manage/v1/$metadata", serviceRootURL)
+	authorization, err := buildRootAuthorizationHeader(host, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchMetadata(url, authorization, refresh)
+}
+
 func InstanceAPIGet(host, instance, user, password, path string) (map[string]any, error) {
 	// Grab the instance root url
 	instanceRootURL, err := GetInstanceRootURL(host, instance)
@@ -341,6 +472,23 @@ func InstanceAPIDelete(host, instance, user, password, path string) error {
 	return internalDelete(url, authorization)
 }
 
+func InstanceAPIMetadata(host, instance, user, password string, refresh bool) (*Metadata, error) {
+	// Grab the instance root url
+	instanceRootURL, err := GetInstanceRootURL(host, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build URL and authorization header (user)
+	url := fmt.Sprintf("%s/$metadata", instanceRootURL)
+	authorization, err := buildUserAuthorizationHeader(user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchMetadata(url, authorization, refresh)
+}
+
 func DatabaseAPIGet(host, instance, database, user, password, path string) (map[string]any, error) {
 	// Grab the database root url
 	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
@@ -358,7 +506,24 @@ func DatabaseAPIGet(host, instance, database, user, password, path string) (map[
 	return internalGet(url, authorization)
 }
 
-func DatabaseAPIPost(host, instance, database, user, password, path string, payload map[string]any) (map[string]any, error) {
+func DatabaseAPIGetContent(host, instance, database, user, password, path string) ([]byte, error) {
+	// Grab the database root url
+	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build URL and authorization header (user)
+	url := fmt.Sprintf("%s/%s", databaseRootURL, path)
+	authorization, err := buildUserAuthorizationHeader(user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return internalGetRaw(url, authorization, "*/*")
+}
+
+func DatabaseAPIPost(host, instance, database, user, password, path string, payload any) (map[string]any, error) {
 	// Grab the database root url
 	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
 	if err != nil {
@@ -375,6 +540,23 @@ func DatabaseAPIPost(host, instance, database, user, password, path string, payl
 	return internalPost(url, authorization, payload)
 }
 
+func DatabaseAPIPatch(host, instance, database, user, password, path string, payload map[string]any) (map[string]any, error) {
+	// Grab the database root url
+	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build URL and authorization header (user)
+	url := fmt.Sprintf("%s/%s", databaseRootURL, path)
+	authorization, err := buildUserAuthorizationHeader(user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return internalPatch(url, authorization, payload)
+}
+
 func DatabaseAPIDelete(host, instance, database, user, password, path string) error {
 	// Grab the database root url
 	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
@@ -408,3 +590,34 @@ func DatabaseAPIPutFile(host, instance, database, user, password, path, file str
 
 	return internalPutFile(url, authorization, file)
 }
+
+func DatabaseAPIMetadata(host, instance, database, user, password string, refresh bool) (*Metadata, error) {
+	// Grab the database root url
+	databaseRootURL, err := GetDatabaseRootURL(host, instance, database)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build URL and authorization header (user)
+	url := fmt.Sprintf("%s/$metadata", databaseRootURL)
+	authorization, err := buildUserAuthorizationHeader(user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchMetadata(url, authorization, refresh)
+}
+
+// DatabaseActionPath returns the path invoking the action bound to the entity, or collection, the path refers to, or
+// the unbound action if the path is empty, the action being qualified as declared in the $metadata document of the
+// database. If the document can't be retrieved the action is qualified with the default alias.
+func DatabaseActionPath(host, instance, database, user, password, path, action string) string {
+	qualified := defaultActionAlias + "." + action
+	if md, err := DatabaseAPIMetadata(host, instance, database, user, password, false); err == nil {
+		qualified = md.QualifiedAction(action)
+	}
+	if path == "" {
+		return qualified
+	}
+	return path + "/" + qualified
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// LogFetcher retrieves log entries, in chronological order, logged at or after the time specified or, if no time is
+// specified, the most recent entries
+type LogFetcher func(since string) ([]any, error)
+
+// FollowLog outputs the most recent log entries and keeps polling for, and outputting, the entries logged since,
+// until interrupted or terminated. In table format, on a terminal, entries are output as lines, as formatted by line, and as NDJSON
+// otherwise. The entries are expected to be ordered by the time held by the time property.
+func FollowLog(fetch LogFetcher, timeProperty string, line func(entry map[string]any) string) error {
+	interval := viper.GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval specified: %s", interval)
+	}
+	lines := viper.GetString("output-format") == "table" && IsTerminal(os.Stdout)
+
+	ctx, stop := InterruptContext()
+	defer stop()
+
+	// Polling for the entries logged at, or after, the time of the last entry output returns that entry, and any
+	// other entry logged at that same time, again, the entries at that time already output are therefore remembered
+	var since string
+	seen := make(map[string]bool)
+	for {
+		entries, err := fetch(since)
+		if err != nil {
+			if since == "" {
+				return err
+			}
+			// Errors, presumably transient, are reported but don't end following
+			fmt.Fprintf(os.Stderr, "%s Error: %v\n", time.Now().Format(time.TimeOnly), err)
+		}
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if at := Stringify(entry[timeProperty]); at != since {
+				since = at
+				seen = make(map[string]bool)
+			}
+			if seen[string(data)] {
+				continue
+			}
+			seen[string(data)] = true
+			if lines {
+				fmt.Println(line(entry))
+			} else {
+				fmt.Println(string(data))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
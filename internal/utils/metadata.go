@@ -0,0 +1,503 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The structures below represent the subset of the CSDL (OData's $metadata document) we use
+type csdlDocument struct {
+	XMLName      xml.Name     `xml:"Edmx"`
+	DataServices csdlServices `xml:"DataServices"`
+}
+
+type csdlServices struct {
+	Schemas []CSDLSchema `xml:"Schema"`
+}
+
+type CSDLSchema struct {
+	Namespace    string               `xml:"Namespace,attr"`
+	Alias        string               `xml:"Alias,attr"`
+	EntityTypes  []*CSDLStructureType `xml:"EntityType"`
+	ComplexTypes []*CSDLStructureType `xml:"ComplexType"`
+	EnumTypes    []*CSDLEnumType      `xml:"EnumType"`
+	Actions      []*CSDLOperation     `xml:"Action"`
+	Functions    []*CSDLOperation     `xml:"Function"`
+	Containers   []CSDLContainer      `xml:"EntityContainer"`
+}
+
+type CSDLStructureType struct {
+	Name                 string                   `xml:"Name,attr"`
+	BaseType             string                   `xml:"BaseType,attr"`
+	Abstract             bool                     `xml:"Abstract,attr"`
+	Key                  []CSDLPropertyRef        `xml:"Key>PropertyRef"`
+	Properties           []CSDLProperty           `xml:"Property"`
+	NavigationProperties []CSDLNavigationProperty `xml:"NavigationProperty"`
+	namespace            string
+}
+
+type CSDLPropertyRef struct {
+	Name string `xml:"Name,attr"`
+}
+
+type CSDLProperty struct {
+	Name     string `xml:"Name,attr"`
+	Type     string `xml:"Type,attr"`
+	Nullable string `xml:"Nullable,attr"`
+}
+
+type CSDLNavigationProperty struct {
+	Name           string `xml:"Name,attr"`
+	Type           string `xml:"Type,attr"`
+	Nullable       string `xml:"Nullable,attr"`
+	ContainsTarget bool   `xml:"ContainsTarget,attr"`
+}
+
+type CSDLEnumType struct {
+	Name    string           `xml:"Name,attr"`
+	IsFlags bool             `xml:"IsFlags,attr"`
+	Members []CSDLEnumMember `xml:"Member"`
+}
+
+type CSDLEnumMember struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+type CSDLOperation struct {
+	Name       string          `xml:"Name,attr"`
+	IsBound    bool            `xml:"IsBound,attr"`
+	Parameters []CSDLParameter `xml:"Parameter"`
+	ReturnType *CSDLReturnType `xml:"ReturnType"`
+	Kind       string          `xml:"-"`
+}
+
+type CSDLParameter struct {
+	Name     string `xml:"Name,attr"`
+	Type     string `xml:"Type,attr"`
+	Nullable string `xml:"Nullable,attr"`
+}
+
+type CSDLReturnType struct {
+	Type string `xml:"Type,attr"`
+}
+
+type CSDLContainer struct {
+	Name       string          `xml:"Name,attr"`
+	EntitySets []CSDLEntitySet `xml:"EntitySet"`
+	Singletons []CSDLSingleton `xml:"Singleton"`
+}
+
+type CSDLEntitySet struct {
+	Name       string `xml:"Name,attr"`
+	EntityType string `xml:"EntityType,attr"`
+}
+
+type CSDLSingleton struct {
+	Name string `xml:"Name,attr"`
+	Type string `xml:"Type,attr"`
+}
+
+// Metadata is the parsed representation of a service's $metadata document with the lookups we need
+type Metadata struct {
+	Schemas    []CSDLSchema
+	structures map[string]*CSDLStructureType
+	enums      map[string]*CSDLEnumType
+	operations []*CSDLOperation
+	entitySets map[string]string
+	aliases    map[string]string
+}
+
+func ParseMetadata(data []byte) (*Metadata, error) {
+	var doc csdlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse $metadata document: %w", err)
+	}
+
+	md := &Metadata{
+		Schemas:    doc.DataServices.Schemas,
+		structures: make(map[string]*CSDLStructureType),
+		enums:      make(map[string]*CSDLEnumType),
+		entitySets: make(map[string]string),
+		aliases:    make(map[string]string),
+	}
+	for _, schema := range md.Schemas {
+		if schema.Alias != "" {
+			md.aliases[schema.Alias] = schema.Namespace
+		}
+	}
+	for _, schema := range md.Schemas {
+		for _, t := range schema.EntityTypes {
+			t.namespace = schema.Namespace
+			md.structures[schema.Namespace+"."+t.Name] = t
+		}
+		for _, t := range schema.ComplexTypes {
+			t.namespace = schema.Namespace
+			md.structures[schema.Namespace+"."+t.Name] = t
+		}
+		for _, t := range schema.EnumTypes {
+			md.enums[schema.Namespace+"."+t.Name] = t
+		}
+		for _, op := range schema.Actions {
+			op.Kind = "Action"
+			md.operations = append(md.operations, op)
+		}
+		for _, op := range schema.Functions {
+			op.Kind = "Function"
+			md.operations = append(md.operations, op)
+		}
+		for _, container := range schema.Containers {
+			for _, set := range container.EntitySets {
+				md.entitySets[set.Name] = md.qualify(set.EntityType)
+			}
+			for _, singleton := range container.Singletons {
+				md.entitySets[singleton.Name] = md.qualify(singleton.Type)
+			}
+		}
+	}
+	return md, nil
+}
+
+// qualify replaces any alias used in a qualified type name with the namespace it stands for
+func (md *Metadata) qualify(name string) string {
+	if idx := strings.LastIndex(name, "."); idx > 0 {
+		if ns, ok := md.aliases[name[:idx]]; ok {
+			return ns + name[idx:]
+		}
+	}
+	return name
+}
+
+// ElementType strips the Collection() wrapper, if any, from a type name
+func ElementType(typeName string) (string, bool) {
+	if strings.HasPrefix(typeName, "Collection(") && strings.HasSuffix(typeName, ")") {
+		return typeName[len("Collection(") : len(typeName)-1], true
+	}
+	return typeName, false
+}
+
+// StructureType looks up an entity or complex type by its qualified name, its simple name or the name
+// of the entity set or singleton exposing it
+func (md *Metadata) StructureType(name string) *CSDLStructureType {
+	name, _ = ElementType(name)
+	if t, ok := md.structures[md.qualify(name)]; ok {
+		return t
+	}
+	if typeName, ok := md.entitySets[name]; ok {
+		return md.structures[typeName]
+	}
+	for _, t := range md.structures {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// EnumType looks up an enumeration type by its qualified name
+func (md *Metadata) EnumType(name string) *CSDLEnumType {
+	return md.enums[md.qualify(name)]
+}
+
+// QualifiedName returns the namespace qualified name of the type
+func (t *CSDLStructureType) QualifiedName() string {
+	return t.namespace + "." + t.Name
+}
+
+// typeChain returns the type and all its base types, the most basic type first
+func (md *Metadata) typeChain(t *CSDLStructureType) []*CSDLStructureType {
+	var chain []*CSDLStructureType
+	for t != nil && len(chain) < 32 {
+		chain = append([]*CSDLStructureType{t}, chain...)
+		if t.BaseType == "" {
+			break
+		}
+		t = md.structures[md.qualify(t.BaseType)]
+	}
+	return chain
+}
+
+// Properties returns the structural properties, including the inherited ones, in declared order
+func (md *Metadata) Properties(t *CSDLStructureType) []CSDLProperty {
+	var props []CSDLProperty
+	for _, bt := range md.typeChain(t) {
+		props = append(props, bt.Properties...)
+	}
+	return props
+}
+
+// NavigationProperties returns the navigation properties, including the inherited ones, in declared order
+func (md *Metadata) NavigationProperties(t *CSDLStructureType) []CSDLNavigationProperty {
+	var props []CSDLNavigationProperty
+	for _, bt := range md.typeChain(t) {
+		props = append(props, bt.NavigationProperties...)
+	}
+	return props
+}
+
+// Keys returns the names of the key properties of the type
+func (md *Metadata) Keys(t *CSDLStructureType) []string {
+	var keys []string
+	for _, bt := range md.typeChain(t) {
+		for _, ref := range bt.Key {
+			keys = append(keys, ref.Name)
+		}
+	}
+	return keys
+}
+
+// BoundOperations returns the actions and functions bound to the type, or any of its base types
+func (md *Metadata) BoundOperations(t *CSDLStructureType) []*CSDLOperation {
+	names := make(map[string]bool)
+	for _, bt := range md.typeChain(t) {
+		names[bt.QualifiedName()] = true
+	}
+	var ops []*CSDLOperation
+	for _, op := range md.operations {
+		if !op.IsBound || len(op.Parameters) == 0 {
+			continue
+		}
+		bindingType, _ := ElementType(op.Parameters[0].Type)
+		if names[md.qualify(bindingType)] {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// The alias actions are qualified with if the $metadata document doesn't declare them, being the alias of the
+// namespace of the TM1 schema
+const defaultActionAlias = "tm1"
+
+// QualifiedAction returns the name of the action qualified with the alias, or if none the namespace, of the schema
+// declaring it, the default alias being used for actions the document doesn't declare
+func (md *Metadata) QualifiedAction(name string) string {
+	for _, schema := range md.Schemas {
+		for _, op := range schema.Actions {
+			if op.Name != name {
+				continue
+			}
+			if schema.Alias != "" {
+				return schema.Alias + "." + name
+			}
+			return schema.Namespace + "." + name
+		}
+	}
+	return defaultActionAlias + "." + name
+}
+
+var keyPredicatePattern = regexp.MustCompile(`\(.*\)$`)
+
+// ContextType resolves the structured type of the items described by an @odata.context URL fragment
+// such as '$metadata#Databases('x')/Cubes', '$metadata#Cubes(Name,Rules)/$entity' or
+// '$metadata#Collection(tm1.Cube)'
+func (md *Metadata) ContextType(contextURL string) *CSDLStructureType {
+	idx := strings.Index(contextURL, "#")
+	if idx < 0 {
+		return nil
+	}
+	fragment := strings.TrimSuffix(contextURL[idx+1:], "/$entity")
+	if fragment == "" {
+		return nil
+	}
+	if elementType, ok := ElementType(fragment); ok {
+		return md.StructureType(elementType)
+	}
+
+	segments := strings.Split(fragment, "/")
+	var current *CSDLStructureType
+	for i, segment := range segments {
+		// Strip any key predicate or select list from the segment
+		name := keyPredicatePattern.ReplaceAllString(segment, "")
+		if i == 0 {
+			if typeName, ok := md.entitySets[name]; ok {
+				current = md.structures[typeName]
+			} else {
+				current = md.structures[md.qualify(name)]
+			}
+		} else if current != nil {
+			next := current
+			current = nil
+			for _, nav := range md.NavigationProperties(next) {
+				if nav.Name == name {
+					current = md.StructureType(nav.Type)
+					break
+				}
+			}
+			if current == nil {
+				// Could be a type cast segment
+				current = md.structures[md.qualify(name)]
+			}
+		}
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+var (
+	metadataCache   = make(map[string]*Metadata)
+	metadataCacheMu sync.Mutex
+)
+
+func metadataCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tm1ctl", "metadata"), nil
+}
+
+// The age up to which a cached $metadata document is used without verifying the version of the service
+const metadataCacheTTL = 24 * time.Hour
+
+// metadataCachePrefix returns the prefix of the names of the files caching the $metadata document of the service
+func metadataCachePrefix(metadataURL string) (string, error) {
+	dir, err := metadataCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(metadataURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+"-"), nil
+}
+
+func metadataCacheFile(prefix, version string) string {
+	version = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, version)
+	return prefix + version + ".xml"
+}
+
+// recentMetadataCacheFile returns the file caching the $metadata document of the service that was written, or
+// verified against the version of the service, within the TTL, if any
+func recentMetadataCacheFile(prefix string) string {
+	files, _ := filepath.Glob(prefix + "*.xml")
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) < metadataCacheTTL {
+			return file
+		}
+	}
+	return ""
+}
+
+// fetchMetadata retrieves the $metadata document, using the on-disk cache if possible. A cached document is used
+// as is within its TTL, after which the product version of the service is retrieved to verify it's still current.
+// Documents of services whose version can't be determined aren't cached on disk.
+func fetchMetadata(metadataURL, authorization string, refresh bool) (*Metadata, error) {
+	metadataCacheMu.Lock()
+	defer metadataCacheMu.Unlock()
+
+	if md, ok := metadataCache[metadataURL]; ok && !refresh {
+		return md, nil
+	}
+
+	var data []byte
+	var cacheFile string
+	prefix, err := metadataCachePrefix(metadataURL)
+	if err == nil && !refresh {
+		if file := recentMetadataCacheFile(prefix); file != "" {
+			data, _ = os.ReadFile(file)
+		}
+	}
+	if data == nil && prefix != "" {
+		serviceRoot := strings.TrimSuffix(metadataURL, "$metadata")
+		if version, err := internalGetRaw(serviceRoot+"Configuration/ProductVersion/$value", authorization, "text/plain"); err == nil {
+			if version := strings.TrimSpace(string(version)); version != "" {
+				cacheFile = metadataCacheFile(prefix, version)
+				if !refresh {
+					if data, _ = os.ReadFile(cacheFile); data != nil {
+						// Still current, good for another TTL
+						now := time.Now()
+						_ = os.Chtimes(cacheFile, now, now)
+					}
+				}
+			}
+		}
+	}
+	if data == nil {
+		data, err = internalGetRaw(metadataURL, authorization, "application/xml")
+		if err != nil {
+			return nil, err
+		}
+		if cacheFile != "" {
+			// Failing to cache the document is not fatal, we'll simply retrieve it again next time
+			if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err == nil {
+				stale, _ := filepath.Glob(prefix + "*.xml")
+				for _, file := range stale {
+					_ = os.Remove(file)
+				}
+				_ = os.WriteFile(cacheFile, data, 0o644)
+			}
+		}
+	}
+
+	md, err := ParseMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	metadataCache[metadataURL] = md
+	return md, nil
+}
+
+// metadataURLFromContext returns the URL of the $metadata document referenced by an @odata.context URL
+func metadataURLFromContext(contextURL string) string {
+	if idx := strings.Index(contextURL, "#"); idx >= 0 {
+		return contextURL[:idx]
+	}
+	return contextURL
+}
+
+// resolveContextURL makes a, potentially relative, @odata.context URL absolute using the request URL
+func resolveContextURL(requestURL, contextURL string) string {
+	base, err := url.Parse(requestURL)
+	if err != nil {
+		return contextURL
+	}
+	ref, err := url.Parse(contextURL)
+	if err != nil {
+		return contextURL
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// withinService reports whether the request URL addresses the service described by the $metadata URL, meaning it
+// uses the same scheme and host and its path lies within the service root
+func withinService(requestURL, metadataURL string) bool {
+	request, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	metadata, err := url.Parse(metadataURL)
+	if err != nil || path.Base(metadata.Path) != "$metadata" {
+		return false
+	}
+	if !strings.EqualFold(request.Scheme, metadata.Scheme) || !strings.EqualFold(request.Host, metadata.Host) {
+		return false
+	}
+	return strings.HasPrefix(request.Path, strings.TrimSuffix(metadata.Path, "$metadata"))
+}
+
+// MetadataForContext returns the metadata of the service an @odata.context URL refers to. The service must
+// have been accessed by this process before as the credentials used for that request are reused.
+func MetadataForContext(contextURL string) (*Metadata, error) {
+	metadataURL := metadataURLFromContext(contextURL)
+	authorization, ok := lookupAuthorization(metadataURL)
+	if !ok {
+		return nil, fmt.Errorf("no credentials known for service '%s'", metadataURL)
+	}
+	return fetchMetadata(metadataURL, authorization, false)
+}
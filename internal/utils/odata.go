@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ODataKey returns the key predicate, including the parentheses, for an entity with the specified name, quoting
+// and escaping the name as required
+func ODataKey(name string) string {
+	return "(" + ODataKeyValue(name) + ")"
+}
+
+// ODataKeyValue returns the name as a, quoted and escaped, value to be used in a (compound) key predicate
+func ODataKeyValue(name string) string {
+	return "'" + url.PathEscape(strings.ReplaceAll(name, "'", "''")) + "'"
+}
+
+// ODataString returns the name as a string literal to be used in a query option like $filter
+func ODataString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// MDXName returns the name as a delimited MDX identifier
+func MDXName(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// ODataFilter returns the $filter query option for the expression, escaped to be used in a URL
+func ODataFilter(expression string) string {
+	return "$filter=" + strings.ReplaceAll(url.QueryEscape(expression), "+", "%20")
+}
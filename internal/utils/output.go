@@ -1,14 +1,14 @@
 package utils
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"reflect"
-	"sort"
+	"strconv"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/viper"
 )
 
@@ -18,11 +18,27 @@ func printPrettyJSON(data any) error {
 	return enc.Encode(data)
 }
 
+func printNDJSON(data any) error {
+	enc := json.NewEncoder(os.Stdout)
+	if list, ok := data.([]any); ok {
+		for _, item := range list {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return enc.Encode(data)
+}
+
 func Stringify(v any) string {
 	switch val := v.(type) {
 	case string:
 		return val
-	case float64, bool, int:
+	case float64:
+		// Avoid the exponent notation for large numbers
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool, int:
 		return fmt.Sprint(val)
 	default:
 		b, _ := json.Marshal(val)
@@ -30,73 +46,99 @@ func Stringify(v any) string {
 	}
 }
 
-func printArrayTable(list []any) error {
-	if len(list) == 0 {
-		fmt.Println("No data.")
-		return nil
-	}
-
-	// Collect headers from the first object
-	first := list[0].(map[string]any)
-	headers := make([]string, 0, len(first))
-	for k := range first {
-		headers = append(headers, k)
-	}
-
-	// Softing the 'headers' for consistency, idealy they'd use the predefined order from the CSDL and, perhaps later, whatever is specified in a select option
-	sort.Strings(headers)
-
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(headers)
-
-	for _, item := range list {
-		row := make([]string, len(headers))
-		obj := item.(map[string]any)
-		for i, key := range headers {
-			row[i] = Stringify(obj[key])
-		}
-		table.Append(row)
+func printTable(data any, contextURL string, columns []string) error {
+	switch val := data.(type) {
+	case []any:
+		return printArrayTable(val, contextURL, columns)
+	case map[string]any:
+		// Wrap the object as a one-item array
+		return printArrayTable([]any{val}, contextURL, columns)
+	default:
+		return fmt.Errorf("unsupported data type: %s", reflect.TypeOf(data))
 	}
-
-	table.Render()
-	return nil
 }
 
-func printTable(data any) error {
+// printCSV outputs the list of objects, or the object, as CSV with a header row, the columns being determined the
+// same way as for tables but the values not being formatted
+func printCSV(data any, contextURL string, columns []string) error {
+	var list []any
 	switch val := data.(type) {
 	case []any:
-		return printArrayTable(val)
+		list = val
 	case map[string]any:
-		// Wrap the object as a one-item array
-		return printArrayTable([]any{val})
+		list = []any{val}
 	default:
 		return fmt.Errorf("unsupported data type: %s", reflect.TypeOf(data))
 	}
+
+	rows := flattenRows(list)
+	var layout *tableLayout
+	if len(columns) > 0 {
+		layout = layoutForColumns(columns, contextURL)
+	} else {
+		layout = layoutForContext(rowProperties(rows), contextURL)
+	}
+	header := make([]string, len(layout.Columns))
+	for i, column := range layout.Columns {
+		header[i] = column.Name
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, name := range header {
+			if val, ok := row[name]; ok && val != nil {
+				record[i] = Stringify(val)
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
 }
 
-func Output(data any) error {
+func output(data any, contextURL string, columns []string) error {
 	switch viper.GetString("output-format") {
 	case "table":
-		return printTable(data)
+		return printTable(data, contextURL, columns)
 	case "json":
 		return printPrettyJSON(data)
+	case "ndjson":
+		return printNDJSON(data)
+	case "csv":
+		return printCSV(data, contextURL, columns)
 	}
-	return fmt.Errorf("invalid output format specified: %s", viper.GetString("output-format"))
+	return UsageError("invalid output format specified: %s", viper.GetString("output-format"))
+}
+
+func Output(data any) error {
+	return output(data, "", nil)
+}
+
+// OutputRows outputs a list of objects, which in table format are rendered using the specified columns in order
+func OutputRows(rows []any, columns ...string) error {
+	return output(rows, "", columns)
 }
 
-func OutputEntity(data any) error {
+// OutputEntity outputs the entity in the response, in table format the columns, if specified, are shown in order
+// instead of the properties of the entity
+func OutputEntity(data any, columns ...string) error {
 	obj, ok := data.(map[string]any)
 	if !ok {
 		return errors.New("expected object at top level")
 	}
 
 	// Remove the @odata.context control information
+	contextURL, _ := obj["@odata.context"].(string)
 	delete(obj, "@odata.context")
 
-	return Output(obj)
+	return output(obj, contextURL, columns)
 }
 
-func OutputCollection(data any) error {
+// OutputCollection outputs the collection in the response, in table format the columns, if specified, are shown
+// in order instead of the properties of the entities
+func OutputCollection(data any, columns ...string) error {
 	obj, ok := data.(map[string]any)
 	if !ok {
 		return errors.New("expected object at top level to extract 'value'")
@@ -107,7 +149,8 @@ func OutputCollection(data any) error {
 		return errors.New("'value' not found in response")
 	}
 
-	return Output(selected)
+	contextURL, _ := obj["@odata.context"].(string)
+	return output(selected, contextURL, columns)
 }
 
 func OutputMap(data map[string]any, keyPropName string) error {
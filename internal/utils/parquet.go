@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A minimal writer of Parquet files, supporting just what is needed to export data: flat schemas of required
+// string and double columns, written using the plain encoding without compression, a single data page per column
+// per row group. The file metadata is encoded using the Thrift compact protocol, as specified by the Parquet format.
+
+// The physical types, and the converted type, used by the Parquet writer
+const (
+	ParquetDouble    = 5 // DOUBLE
+	ParquetString    = 6 // BYTE_ARRAY, annotated as UTF8
+	parquetUTF8      = 0 // The UTF8 converted type
+	parquetPlain     = 0 // The PLAIN encoding
+	parquetRLE       = 3 // The RLE encoding, used for the, absent, definition and repetition levels
+	parquetDataPage  = 0 // The DATA_PAGE page type
+	parquetCreatedBy = "tm1ctl"
+)
+
+// The Thrift compact protocol types used in the file metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// ParquetColumn is a column of a Parquet file, its type either ParquetString or ParquetDouble
+type ParquetColumn struct {
+	Name string
+	Type int
+}
+
+// ParquetWriter writes rows to a Parquet file, buffering the rows of a row group until flushed
+type ParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []ParquetColumn
+	values    []*bytes.Buffer
+	rows      int64
+	totalRows int64
+	rowGroups []*thriftWriter
+}
+
+// NewParquetWriter returns a writer writing a Parquet file with the columns specified to w
+func NewParquetWriter(w io.Writer, columns []ParquetColumn) (*ParquetWriter, error) {
+	p := &ParquetWriter{w: w, columns: columns, values: make([]*bytes.Buffer, len(columns))}
+	for i := range p.values {
+		p.values[i] = &bytes.Buffer{}
+	}
+	return p, p.write([]byte("PAR1"))
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// WriteRow adds a row, holding a string value for every string column and a float64 for every double column
+func (p *ParquetWriter) WriteRow(row []any) error {
+	if len(row) != len(p.columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(row), len(p.columns))
+	}
+	for i, column := range p.columns {
+		switch column.Type {
+		case ParquetString:
+			s, ok := row[i].(string)
+			if !ok {
+				return fmt.Errorf("value of column '%s' is not a string", column.Name)
+			}
+			binary.Write(p.values[i], binary.LittleEndian, uint32(len(s)))
+			p.values[i].WriteString(s)
+		case ParquetDouble:
+			f, ok := row[i].(float64)
+			if !ok {
+				return fmt.Errorf("value of column '%s' is not a number", column.Name)
+			}
+			binary.Write(p.values[i], binary.LittleEndian, math.Float64bits(f))
+		}
+	}
+	p.rows++
+	return nil
+}
+
+// Flush writes the rows added since the previous flush as a row group
+func (p *ParquetWriter) Flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+	rowGroup := &thriftWriter{}
+	rowGroup.fieldList(1, thriftStruct, len(p.columns))
+	var totalSize int64
+	for i, column := range p.columns {
+		data := p.values[i].Bytes()
+
+		header := &thriftWriter{}
+		header.fieldI32(1, parquetDataPage)
+		header.fieldI32(2, int32(len(data)))
+		header.fieldI32(3, int32(len(data)))
+		header.fieldStruct(5)
+		header.fieldI32(1, int32(p.rows))
+		header.fieldI32(2, parquetPlain)
+		header.fieldI32(3, parquetRLE)
+		header.fieldI32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		pageOffset := p.offset
+		if err := p.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(data); err != nil {
+			return err
+		}
+		size := int64(header.buf.Len() + len(data))
+		totalSize += size
+
+		// ColumnChunk, holding the ColumnMetaData
+		rowGroup.beginListStruct()
+		rowGroup.fieldI64(2, pageOffset)
+		rowGroup.fieldStruct(3)
+		rowGroup.fieldI32(1, int32(column.Type))
+		rowGroup.fieldList(2, thriftI32, 1)
+		rowGroup.zigzag(parquetPlain)
+		rowGroup.fieldList(3, thriftBinary, 1)
+		rowGroup.binary(column.Name)
+		rowGroup.fieldI32(4, 0)
+		rowGroup.fieldI64(5, p.rows)
+		rowGroup.fieldI64(6, size)
+		rowGroup.fieldI64(7, size)
+		rowGroup.fieldI64(9, pageOffset)
+		rowGroup.endStruct()
+		rowGroup.endStruct()
+
+		p.values[i].Reset()
+	}
+	rowGroup.fieldI64(2, totalSize)
+	rowGroup.fieldI64(3, p.rows)
+	rowGroup.endStruct()
+
+	p.rowGroups = append(p.rowGroups, rowGroup)
+	p.totalRows += p.rows
+	p.rows = 0
+	return nil
+}
+
+// Close flushes any remaining rows and writes the file metadata, it does not close the underlying writer
+func (p *ParquetWriter) Close() error {
+	if err := p.Flush(); err != nil {
+		return err
+	}
+	meta := &thriftWriter{}
+	meta.fieldI32(1, 1)
+	meta.fieldList(2, thriftStruct, len(p.columns)+1)
+	meta.beginListStruct()
+	meta.fieldBinary(4, "schema")
+	meta.fieldI32(5, int32(len(p.columns)))
+	meta.endStruct()
+	for _, column := range p.columns {
+		meta.beginListStruct()
+		meta.fieldI32(1, int32(column.Type))
+		meta.fieldI32(3, 0) // REQUIRED
+		meta.fieldBinary(4, column.Name)
+		if column.Type == ParquetString {
+			meta.fieldI32(6, parquetUTF8)
+		}
+		meta.endStruct()
+	}
+	meta.fieldI64(3, p.totalRows)
+	meta.fieldList(4, thriftStruct, len(p.rowGroups))
+	for _, rowGroup := range p.rowGroups {
+		// The row groups are encoded as complete structs already, list elements not affecting the field ids
+		meta.buf.Write(rowGroup.buf.Bytes())
+	}
+	meta.fieldBinary(6, parquetCreatedBy)
+	meta.endStruct()
+
+	footer := binary.LittleEndian.AppendUint32(meta.buf.Bytes(), uint32(meta.buf.Len()))
+	return p.write(append(footer, "PAR1"...))
+}
+
+// thriftWriter encodes a struct using the Thrift compact protocol, keeping track of the last field id written in
+// the, nested, structs being written
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int
+	stack []int
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) fieldHeader(id, typ int) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta<<4 | typ))
+	} else {
+		t.buf.WriteByte(byte(typ))
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) fieldI32(id int, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) fieldI64(id int, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) fieldBinary(id int, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(s)
+}
+
+func (t *thriftWriter) fieldList(id, elementType, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size<<4 | elementType))
+	} else {
+		t.buf.WriteByte(byte(0xf0 | elementType))
+		t.varint(uint64(size))
+	}
+}
+
+// fieldStruct starts a struct field, to be ended using endStruct
+func (t *thriftWriter) fieldStruct(id int) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginListStruct()
+}
+
+// beginListStruct starts a struct, being a list element, to be ended using endStruct
+func (t *thriftWriter) beginListStruct() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	if len(t.stack) > 0 {
+		t.last = t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
@@ -0,0 +1,288 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// thriftReader decodes structs encoded using the Thrift compact protocol into maps of field values by field id,
+// lists being decoded as []any, binaries as strings and integers as int64
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (t *thriftReader) byte() byte {
+	b := t.buf[t.pos]
+	t.pos++
+	return b
+}
+
+func (t *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(t.buf[t.pos:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	t.pos += n
+	return v
+}
+
+func (t *thriftReader) zigzag() int64 {
+	v := t.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (t *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return t.zigzag()
+	case thriftBinary:
+		n := int(t.varint())
+		s := string(t.buf[t.pos : t.pos+n])
+		t.pos += n
+		return s
+	case thriftList:
+		header := t.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(t.varint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = t.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return t.structure()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+func (t *thriftReader) structure() map[int]any {
+	fields := make(map[int]any)
+	last := 0
+	for {
+		header := t.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int(header>>4)
+		if header>>4 == 0 {
+			id = int(t.zigzag())
+		}
+		fields[id] = t.value(header & 0x0f)
+		last = id
+	}
+}
+
+// parquetFile is the content of a Parquet file as decoded by readParquet
+type parquetFile struct {
+	names     []string
+	types     []int64
+	rows      int64
+	rowGroups []int64
+	columns   [][]any
+}
+
+// readParquet decodes a Parquet file, as written by ParquetWriter, validating its structure along the way
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("missing PAR1 magic")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{buf: data[len(data)-8-size : len(data)-8]}
+	meta := footer.structure()
+	if footer.pos != size {
+		t.Fatalf("footer holds %d bytes, %d decoded", size, footer.pos)
+	}
+	if meta[1] != int64(1) {
+		t.Errorf("version = %v, want 1", meta[1])
+	}
+	if meta[6] != parquetCreatedBy {
+		t.Errorf("created by = %v, want %s", meta[6], parquetCreatedBy)
+	}
+
+	f := &parquetFile{rows: meta[3].(int64)}
+	schema := meta[2].([]any)
+	root := schema[0].(map[int]any)
+	if root[5] != int64(len(schema)-1) {
+		t.Fatalf("root schema element has %v children, want %d", root[5], len(schema)-1)
+	}
+	for _, raw := range schema[1:] {
+		element := raw.(map[int]any)
+		f.names = append(f.names, element[4].(string))
+		f.types = append(f.types, element[1].(int64))
+	}
+	f.columns = make([][]any, len(f.names))
+
+	for _, raw := range meta[4].([]any) {
+		rowGroup := raw.(map[int]any)
+		rows := rowGroup[3].(int64)
+		f.rowGroups = append(f.rowGroups, rows)
+		chunks := rowGroup[1].([]any)
+		if len(chunks) != len(f.names) {
+			t.Fatalf("row group holds %d column chunks, want %d", len(chunks), len(f.names))
+		}
+		for i, rawChunk := range chunks {
+			columnMeta := rawChunk.(map[int]any)[3].(map[int]any)
+			if columnMeta[3].([]any)[0] != f.names[i] {
+				t.Errorf("column chunk %d is of column %v, want %s", i, columnMeta[3], f.names[i])
+			}
+			if columnMeta[5] != rows {
+				t.Errorf("column chunk %d holds %v values, want %d", i, columnMeta[5], rows)
+			}
+			offset := int(columnMeta[9].(int64))
+			page := &thriftReader{buf: data, pos: offset}
+			header := page.structure()
+			if int64(page.pos-offset)+header[3].(int64) != columnMeta[7] {
+				t.Errorf("column chunk %d size %v does not match its page", i, columnMeta[7])
+			}
+			if header[5].(map[int]any)[1] != rows {
+				t.Errorf("data page of column %d holds %v values, want %d", i, header[5].(map[int]any)[1], rows)
+			}
+			values := data[page.pos : page.pos+int(header[3].(int64))]
+			for n := int64(0); n < rows; n++ {
+				if f.types[i] == ParquetDouble {
+					f.columns[i] = append(f.columns[i], math.Float64frombits(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+				} else {
+					length := binary.LittleEndian.Uint32(values)
+					f.columns[i] = append(f.columns[i], string(values[4:4+length]))
+					values = values[4+length:]
+				}
+			}
+			if len(values) != 0 {
+				t.Errorf("data page of column %d holds %d trailing bytes", i, len(values))
+			}
+		}
+	}
+	return f
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	columns := []ParquetColumn{{Name: "Year", Type: ParquetString}, {Name: "Region", Type: ParquetString}, {Name: "Value", Type: ParquetDouble}}
+	rows := [][]any{
+		{"2024", "NL", 1234.5},
+		{"2024", "DE", -7.25},
+		{"2025", "", 0.0},
+		{"2025", "Zürich", math.MaxFloat64},
+	}
+
+	var buf bytes.Buffer
+	p, err := NewParquetWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := p.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := readParquet(t, buf.Bytes())
+	if f.rows != int64(len(rows)) {
+		t.Errorf("rows = %d, want %d", f.rows, len(rows))
+	}
+	for i, column := range columns {
+		if f.names[i] != column.Name || f.types[i] != int64(column.Type) {
+			t.Errorf("column %d = %s (%d), want %s (%d)", i, f.names[i], f.types[i], column.Name, column.Type)
+		}
+		for j, row := range rows {
+			if f.columns[i][j] != row[i] {
+				t.Errorf("row %d column %s = %v, want %v", j, column.Name, f.columns[i][j], row[i])
+			}
+		}
+	}
+}
+
+func TestParquetManyColumnsAndRowGroups(t *testing.T) {
+	// Lists of 15 elements or more, the column chunks and row groups as well as the schema, use the long list header
+	for _, size := range []int{14, 15, 16} {
+		t.Run(fmt.Sprintf("%d columns and row groups", size), func(t *testing.T) {
+			testParquetSize(t, size)
+		})
+	}
+}
+
+// testParquetSize writes, and reads back, a file with size columns, the last one numeric, and size row groups
+func testParquetSize(t *testing.T, size int) {
+	var columns []ParquetColumn
+	for i := 1; i < size; i++ {
+		columns = append(columns, ParquetColumn{Name: fmt.Sprintf("Dimension %d", i), Type: ParquetString})
+	}
+	columns = append(columns, ParquetColumn{Name: "Value", Type: ParquetDouble})
+	last := len(columns) - 1
+
+	var buf bytes.Buffer
+	p, err := NewParquetWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for group := 0; group < size; group++ {
+		for n := 0; n <= group; n++ {
+			row := make([]any, len(columns))
+			for i := 0; i < last; i++ {
+				row[i] = fmt.Sprintf("e%d-%d", i, total)
+			}
+			row[last] = float64(total)
+			if err := p.WriteRow(row); err != nil {
+				t.Fatal(err)
+			}
+			total++
+		}
+		if err := p.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Flushing without any rows added doesn't add an empty row group
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := readParquet(t, buf.Bytes())
+	if len(f.names) != len(columns) {
+		t.Fatalf("columns = %d, want %d", len(f.names), len(columns))
+	}
+	if f.rows != int64(total) {
+		t.Errorf("rows = %d, want %d", f.rows, total)
+	}
+	if len(f.rowGroups) != size {
+		t.Fatalf("row groups = %d, want %d", len(f.rowGroups), size)
+	}
+	for group, rows := range f.rowGroups {
+		if rows != int64(group+1) {
+			t.Errorf("row group %d holds %d rows, want %d", group, rows, group+1)
+		}
+	}
+	for n := 0; n < total; n++ {
+		if want := fmt.Sprintf("e%d-%d", last-1, n); f.columns[last-1][n] != want {
+			t.Errorf("row %d of column %d = %v, want %s", n, last, f.columns[last-1][n], want)
+		}
+		if f.columns[last][n] != float64(n) {
+			t.Errorf("row %d of the value column = %v, want %d", n, f.columns[last][n], n)
+		}
+	}
+}
+
+func TestParquetWriteRowValidation(t *testing.T) {
+	p, err := NewParquetWriter(&bytes.Buffer{}, []ParquetColumn{{Name: "Name", Type: ParquetString}, {Name: "Value", Type: ParquetDouble}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{{"a"}, {1.0, 1.0}, {"a", "1"}} {
+		if err := p.WriteRow(row); err == nil {
+			t.Errorf("expected an error writing %v", row)
+		}
+	}
+}
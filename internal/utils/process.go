@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The supported formats of the header of a process file set
+const (
+	ProcessFormatYAML = "yaml"
+	ProcessFormatJSON = "json"
+)
+
+// ProcessQuery selects the properties making up the definition of a process, as kept in a process file set
+const ProcessQuery = "$select=Name,HasSecurityAccess,DataSource,Parameters,Variables,PrologProcedure,MetadataProcedure,DataProcedure,EpilogProcedure"
+
+// The name, without extension, of the file holding the header of a process file set
+const processHeaderFile = "process"
+
+// The files holding the code of the procedures of a process, by the property holding the procedure
+var processProcedureFiles = []struct {
+	Property string
+	File     string
+}{
+	{"PrologProcedure", "prolog.ti"},
+	{"MetadataProcedure", "metadata.ti"},
+	{"DataProcedure", "data.ti"},
+	{"EpilogProcedure", "epilog.ti"},
+}
+
+// processHeader is the header of a process file set, holding all but the code of the process, declared in the order
+// in which the properties are written
+type processHeader struct {
+	Name              string           `json:"Name" yaml:"Name"`
+	HasSecurityAccess bool             `json:"HasSecurityAccess" yaml:"HasSecurityAccess"`
+	DataSource        map[string]any   `json:"DataSource,omitempty" yaml:"DataSource,omitempty"`
+	Parameters        []map[string]any `json:"Parameters" yaml:"Parameters"`
+	Variables         []map[string]any `json:"Variables" yaml:"Variables"`
+}
+
+// setDefaults makes sure the header holds, possibly empty, lists of parameters and variables
+func (h *processHeader) setDefaults() {
+	if h.Parameters == nil {
+		h.Parameters = []map[string]any{}
+	}
+	if h.Variables == nil {
+		h.Variables = []map[string]any{}
+	}
+}
+
+// ProcessDirName returns the name of the directory holding the file set of the process, replacing the characters
+// not allowed in file names on any platform
+func ProcessDirName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// normalizeCode returns the code using newlines only as line endings and without trailing line endings
+func normalizeCode(code any) string {
+	s, _ := code.(string)
+	return strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// convert converts the value into the target by means of its JSON representation
+func convert(value, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// NormalizeProcess returns the definition of the process, as kept in a process file set, the code using newlines
+// only as line endings, allowing definitions to be compared
+func NormalizeProcess(process map[string]any) (map[string]any, error) {
+	var header processHeader
+	if err := convert(process, &header); err != nil {
+		return nil, err
+	}
+	header.setDefaults()
+	normalized := make(map[string]any)
+	if err := convert(header, &normalized); err != nil {
+		return nil, err
+	}
+	for _, procedure := range processProcedureFiles {
+		normalized[procedure.Property] = normalizeCode(process[procedure.Property])
+	}
+	return normalized, nil
+}
+
+// IsProcessDir returns true if the directory holds a process file set
+func IsProcessDir(dir string) bool {
+	for _, format := range []string{ProcessFormatYAML, ProcessFormatJSON} {
+		if _, err := os.Stat(filepath.Join(dir, processHeaderFile+"."+format)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteProcessFiles writes the process as a file set to the directory: the header, in the format specified, and a
+// file for the code of every procedure
+func WriteProcessFiles(dir string, process map[string]any, format string) error {
+	var header processHeader
+	if err := convert(process, &header); err != nil {
+		return err
+	}
+	header.setDefaults()
+	var data []byte
+	var err error
+	switch format {
+	case ProcessFormatJSON:
+		data, err = json.MarshalIndent(header, "", "  ")
+		data = append(data, '\n')
+	case ProcessFormatYAML:
+		data, err = yaml.Marshal(header)
+	default:
+		return UsageError("invalid format '%s', supported formats are: %s and %s", format, ProcessFormatYAML, ProcessFormatJSON)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Remove the header in the other format, if any, so the file set holds a single header
+	for _, other := range []string{ProcessFormatYAML, ProcessFormatJSON} {
+		if other != format {
+			os.Remove(filepath.Join(dir, processHeaderFile+"."+other))
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, processHeaderFile+"."+format), data, 0644); err != nil {
+		return err
+	}
+	for _, procedure := range processProcedureFiles {
+		code := normalizeCode(process[procedure.Property])
+		if code != "" {
+			code += "\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, procedure.File), []byte(code), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadProcessFiles reads the process file set in the directory, returning the definition of the process
+func ReadProcessFiles(dir string) (map[string]any, error) {
+	var header processHeader
+	if data, err := os.ReadFile(filepath.Join(dir, processHeaderFile+"."+ProcessFormatYAML)); err == nil {
+		if err := yaml.Unmarshal(data, &header); err != nil {
+			return nil, UsageError("invalid process header in '%s': %v", dir, err)
+		}
+	} else if data, err := os.ReadFile(filepath.Join(dir, processHeaderFile+"."+ProcessFormatJSON)); err == nil {
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, UsageError("invalid process header in '%s': %v", dir, err)
+		}
+	} else {
+		return nil, UsageError("no process header found in '%s'", dir)
+	}
+	if header.Name == "" {
+		return nil, UsageError("the process header in '%s' holds no name", dir)
+	}
+	header.setDefaults()
+
+	process := make(map[string]any)
+	if err := convert(header, &process); err != nil {
+		return nil, err
+	}
+	for _, procedure := range processProcedureFiles {
+		code, err := os.ReadFile(filepath.Join(dir, procedure.File))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		process[procedure.Property] = normalizeCode(string(code))
+	}
+	return process, nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Confirm asks the user to confirm an action, returning an error if no terminal is available to ask the question
+func Confirm(prompt string) (bool, error) {
+	if !IsTerminal(os.Stdin) {
+		return false, UsageError("%s, confirmation required but no terminal available, use --force to proceed without confirmation", prompt)
+	}
+	fmt.Fprintf(os.Stderr, "%s, continue? [y/N] ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
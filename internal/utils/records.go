@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// The supported record file formats
+const (
+	RecordFormatCSV     = "csv"
+	RecordFormatNDJSON  = "ndjson"
+	RecordFormatParquet = "parquet"
+)
+
+// RecordColumn is a column of a record file, numeric columns holding float64 values and all others strings
+type RecordColumn struct {
+	Name    string
+	Numeric bool
+}
+
+// RecordWriter writes records, holding a value for every column, to a record file
+type RecordWriter interface {
+	Write(record []any) error
+	// Close writes anything still buffered, it does not close the underlying writer
+	Close() error
+}
+
+// RecordFormat returns the record file format, as specified or, if not, based on the extension of the file
+func RecordFormat(format, file string) (string, error) {
+	switch format {
+	case RecordFormatCSV, RecordFormatNDJSON, RecordFormatParquet:
+		return format, nil
+	case "":
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".ndjson", ".jsonl":
+			return RecordFormatNDJSON, nil
+		case ".parquet":
+			return RecordFormatParquet, nil
+		}
+		return RecordFormatCSV, nil
+	}
+	return "", UsageError("invalid format '%s', supported formats are: %s, %s and %s", format, RecordFormatCSV, RecordFormatNDJSON, RecordFormatParquet)
+}
+
+// NewRecordWriter returns a writer writing records with the columns specified, in the format specified, to w
+func NewRecordWriter(w io.Writer, format string, columns []RecordColumn) (RecordWriter, error) {
+	switch format {
+	case RecordFormatNDJSON:
+		return &ndjsonRecordWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	case RecordFormatParquet:
+		parquetColumns := make([]ParquetColumn, len(columns))
+		for i, column := range columns {
+			parquetColumns[i] = ParquetColumn{Name: column.Name, Type: ParquetString}
+			if column.Numeric {
+				parquetColumns[i].Type = ParquetDouble
+			}
+		}
+		writer, err := NewParquetWriter(w, parquetColumns)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetRecordWriter{writer: writer}, nil
+	}
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return &csvRecordWriter{writer: writer}, writer.Write(header)
+}
+
+type csvRecordWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvRecordWriter) Write(record []any) error {
+	row := make([]string, len(record))
+	for i, val := range record {
+		if val != nil {
+			row[i] = Stringify(val)
+		}
+	}
+	return c.writer.Write(row)
+}
+
+func (c *csvRecordWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonRecordWriter struct {
+	encoder *json.Encoder
+	columns []RecordColumn
+}
+
+func (n *ndjsonRecordWriter) Write(record []any) error {
+	object := make(map[string]any, len(record))
+	for i, val := range record {
+		object[n.columns[i].Name] = val
+	}
+	return n.encoder.Encode(object)
+}
+
+func (n *ndjsonRecordWriter) Close() error {
+	return nil
+}
+
+// The number of records written to a row group of a Parquet file
+const parquetRowGroupSize = 100000
+
+type parquetRecordWriter struct {
+	writer *ParquetWriter
+	rows   int
+}
+
+func (p *parquetRecordWriter) Write(record []any) error {
+	if err := p.writer.WriteRow(record); err != nil {
+		return err
+	}
+	if p.rows++; p.rows%parquetRowGroupSize == 0 {
+		return p.writer.Flush()
+	}
+	return nil
+}
+
+func (p *parquetRecordWriter) Close() error {
+	return p.writer.Close()
+}
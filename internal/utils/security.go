@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecurityUser is a user, and the groups it is a member of, as defined in a security file
+type SecurityUser struct {
+	Name         string   `yaml:"Name"`
+	FriendlyName string   `yaml:"FriendlyName"`
+	Groups       []string `yaml:"Groups"`
+}
+
+// ReadSecurityFile reads the users, and their groups, from a security file, '-' reading the file from stdin. Files
+// with the .csv extension are read as CSV files, all others as YAML, or JSON, files holding a list of users. A CSV
+// file holds a Name, or User, column and either a Groups column, the groups separated by semicolons, or a Group
+// column, a row per membership, and optionally a FriendlyName column.
+func ReadSecurityFile(file string) ([]SecurityUser, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, UsageError("unable to read file: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		return readSecurityCSV(r)
+	}
+	var users []SecurityUser
+	if err := yaml.NewDecoder(r).Decode(&users); err != nil && !errors.Is(err, io.EOF) {
+		return nil, UsageError("invalid security file '%s': %v", file, err)
+	}
+	for i, u := range users {
+		if u.Name == "" {
+			return nil, UsageError("user %d in security file '%s' holds no name", i+1, file)
+		}
+	}
+	return users, nil
+}
+
+// readSecurityCSV reads the users, and their groups, from a CSV file, merging the rows of the same user
+func readSecurityCSV(r io.Reader) ([]SecurityUser, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, UsageError("invalid CSV file: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	value := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+	if _, ok := columns["name"]; !ok {
+		if _, ok := columns["user"]; !ok {
+			return nil, UsageError("the CSV file holds no Name, or User, column")
+		}
+	}
+
+	var users []SecurityUser
+	index := make(map[string]int)
+	for line, record := range records[1:] {
+		name := value(record, "name", "user")
+		if name == "" {
+			return nil, UsageError("row %d of the CSV file holds no user name", line+2)
+		}
+		i, ok := index[NameKey(name)]
+		if !ok {
+			i = len(users)
+			index[NameKey(name)] = i
+			users = append(users, SecurityUser{Name: name, Groups: []string{}})
+		}
+		if friendlyName := value(record, "friendlyname"); friendlyName != "" {
+			users[i].FriendlyName = friendlyName
+		}
+		for _, group := range strings.Split(value(record, "groups", "group"), ";") {
+			if group = strings.TrimSpace(group); group != "" {
+				users[i].Groups = append(users[i].Groups, group)
+			}
+		}
+	}
+	return users, nil
+}
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"context"
+	"os/signal"
+)
+
+// InterruptContext returns a context that is done once the process is interrupted, or asked to terminate, allowing
+// commands to clean up, for instance restore what they changed, before exiting
+func InterruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), interruptSignals...)
+}
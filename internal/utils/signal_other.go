@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package utils
+
+import "os"
+
+// The signals interrupting the process
+var interruptSignals = []os.Signal{os.Interrupt}
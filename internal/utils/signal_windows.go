@@ -0,0 +1,11 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// The signals interrupting the process
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
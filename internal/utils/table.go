@@ -0,0 +1,502 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/viper"
+)
+
+const (
+	// The depth up to which nested objects are flattened into dotted columns
+	maxFlattenDepth = 3
+
+	// The name of the column used for list items that aren't objects
+	valueColumnName = "Value"
+
+	// The minimum width a column is reduced to when fitting a table to the width of the terminal
+	minColumnWidth = 6
+)
+
+// tableColumn describes a column of a table, the property it represents and how to format its values
+type tableColumn struct {
+	Name   string
+	Format func(any) string
+}
+
+// tableLayout describes the columns, in order, used to render a table and, optionally, which cells and columns
+// are to be highlighted
+type tableLayout struct {
+	Columns         []tableColumn
+	highlightCell   func(row int, column string) bool
+	highlightColumn func(column string) bool
+}
+
+// highlight marks text as highlighted using reverse video, note that the table writer ignores the escape
+// sequences when determining the width of a cell
+func highlight(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "\033[7m" + line + "\033[0m"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isAnnotation returns true if the property name represents an instance or property annotation (e.g. @odata.etag)
+func isAnnotation(name string) bool {
+	return strings.Contains(name, "@")
+}
+
+// flatten copies the properties of the object into out, nested objects are represented by dotted property names
+func flatten(prefix string, obj map[string]any, out map[string]any, depth int) {
+	for key, val := range obj {
+		if nested, ok := val.(map[string]any); ok && len(nested) > 0 && depth < maxFlattenDepth {
+			flatten(prefix+key+".", nested, out, depth+1)
+		} else {
+			out[prefix+key] = val
+		}
+	}
+}
+
+// flattenRows converts a list of items into a list of flat objects, items that aren't objects are represented by
+// an object with a single Value property
+func flattenRows(list []any) []map[string]any {
+	rows := make([]map[string]any, len(list))
+	for i, item := range list {
+		row := make(map[string]any)
+		if obj, ok := item.(map[string]any); ok {
+			flatten("", obj, row, 0)
+		} else {
+			row[valueColumnName] = item
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// rowProperties returns the union of the properties of all the rows
+func rowProperties(rows []map[string]any) []string {
+	seen := make(map[string]bool)
+	var properties []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				properties = append(properties, key)
+			}
+		}
+	}
+	return properties
+}
+
+// summarizeArray represents an array by its first few items, the number of items shown is controlled by max-items
+func summarizeArray(list []any, format func(any) string) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+	maxItems := viper.GetInt("max-items")
+	if maxItems <= 0 {
+		return fmt.Sprintf("[%d items]", len(list))
+	}
+	items := make([]string, 0, maxItems)
+	for i, item := range list {
+		if i == maxItems {
+			break
+		}
+		switch val := item.(type) {
+		case map[string]any:
+			// Objects are represented by their name or id, if they have one
+			if name, ok := val["Name"]; ok {
+				items = append(items, Stringify(name))
+			} else if id, ok := val["ID"]; ok {
+				items = append(items, Stringify(id))
+			} else {
+				items = append(items, "{…}")
+			}
+		case []any:
+			items = append(items, fmt.Sprintf("[%d items]", len(val)))
+		default:
+			items = append(items, format(val))
+		}
+	}
+	summary := strings.Join(items, ", ")
+	if len(list) > maxItems {
+		summary += fmt.Sprintf(", … (%d items)", len(list))
+	}
+	return summary
+}
+
+// cellFormatter wraps a value formatter such that arrays get summarized and objects rendered compactly
+func cellFormatter(format func(any) string) func(any) string {
+	return func(v any) string {
+		if list, ok := v.([]any); ok {
+			return summarizeArray(list, format)
+		}
+		return format(v)
+	}
+}
+
+// formatterForType returns a function formatting values of the specified EDM, or enumeration, type
+func formatterForType(md *Metadata, typeName string) func(any) string {
+	typeName, _ = ElementType(typeName)
+	switch typeName {
+	case "Edm.DateTimeOffset":
+		return func(v any) string {
+			if s, ok := v.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					return t.Local().Format("2006-01-02 15:04:05")
+				}
+			}
+			return Stringify(v)
+		}
+	case "Edm.Byte", "Edm.SByte", "Edm.Int16", "Edm.Int32", "Edm.Int64":
+		return func(v any) string {
+			if f, ok := v.(float64); ok {
+				return strconv.FormatFloat(f, 'f', 0, 64)
+			}
+			return Stringify(v)
+		}
+	case "Edm.Single", "Edm.Double", "Edm.Decimal":
+		return func(v any) string {
+			if f, ok := v.(float64); ok {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+			return Stringify(v)
+		}
+	}
+	if md != nil {
+		if enum := md.EnumType(typeName); enum != nil {
+			return func(v any) string {
+				// Enumeration values are normally represented by their member name, map numeric values if not
+				if f, ok := v.(float64); ok {
+					value := strconv.FormatFloat(f, 'f', 0, 64)
+					for i, member := range enum.Members {
+						if member.Value == value || (member.Value == "" && strconv.Itoa(i) == value) {
+							return member.Name
+						}
+					}
+				}
+				return Stringify(v)
+			}
+		}
+	}
+	return Stringify
+}
+
+// appendStructureColumns adds the columns for the properties of the structured type, and those of any nested
+// structured types, in the order they are declared in the metadata
+func appendStructureColumns(layout *tableLayout, remaining map[string]bool, prefix string, md *Metadata, structure *CSDLStructureType, depth int) {
+	all := viper.GetBool("all-columns")
+	for _, prop := range md.Properties(structure) {
+		name := prefix + prop.Name
+		if remaining[name] {
+			layout.Columns = append(layout.Columns, tableColumn{Name: name, Format: cellFormatter(formatterForType(md, prop.Type))})
+			delete(remaining, name)
+		}
+		if _, collection := ElementType(prop.Type); !collection && depth < maxFlattenDepth {
+			if nested := md.StructureType(prop.Type); nested != nil {
+				appendStructureColumns(layout, remaining, name+".", md, nested, depth+1)
+			}
+		}
+	}
+	for _, nav := range md.NavigationProperties(structure) {
+		name := prefix + nav.Name
+		if !all {
+			// Navigation properties, including any of their flattened properties, are hidden
+			for key := range remaining {
+				if key == name || strings.HasPrefix(key, name+".") {
+					delete(remaining, key)
+				}
+			}
+			continue
+		}
+		if remaining[name] {
+			layout.Columns = append(layout.Columns, tableColumn{Name: name, Format: cellFormatter(Stringify)})
+			delete(remaining, name)
+		}
+		if _, collection := ElementType(nav.Type); !collection && depth < maxFlattenDepth {
+			if nested := md.StructureType(nav.Type); nested != nil {
+				appendStructureColumns(layout, remaining, name+".", md, nested, depth+1)
+			}
+		}
+	}
+}
+
+// layoutForProperties determines the columns to show for a set of properties. If the structured type is known
+// the properties are ordered as declared in the metadata and formatted according to their types, navigation
+// properties and annotations are hidden unless all columns are requested. Any remaining properties follow, sorted.
+func layoutForProperties(properties []string, md *Metadata, structure *CSDLStructureType) *tableLayout {
+	all := viper.GetBool("all-columns")
+	remaining := make(map[string]bool, len(properties))
+	for _, name := range properties {
+		if all || !isAnnotation(name) {
+			remaining[name] = true
+		}
+	}
+
+	layout := &tableLayout{}
+	if structure != nil {
+		appendStructureColumns(layout, remaining, "", md, structure, 0)
+	}
+	if remaining[valueColumnName] {
+		// Items that aren't objects come before any of the properties not described by the metadata
+		layout.Columns = append(layout.Columns, tableColumn{Name: valueColumnName, Format: cellFormatter(Stringify)})
+		delete(remaining, valueColumnName)
+	}
+
+	// Sorting the remaining 'headers' for consistency
+	rest := make([]string, 0, len(remaining))
+	for name := range remaining {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		layout.Columns = append(layout.Columns, tableColumn{Name: name, Format: cellFormatter(Stringify)})
+	}
+	return layout
+}
+
+// contextStructure returns the metadata, and the structured type, describing the items referenced by a context URL
+func contextStructure(contextURL string) (*Metadata, *CSDLStructureType) {
+	if contextURL == "" {
+		return nil, nil
+	}
+	// Failing to retrieve the metadata is not fatal, we'll simply fall back to the default formatting
+	md, err := MetadataForContext(contextURL)
+	if err != nil {
+		return nil, nil
+	}
+	return md, md.ContextType(contextURL)
+}
+
+// layoutForContext determines the layout for the properties using the metadata referenced by the context URL
+func layoutForContext(properties []string, contextURL string) *tableLayout {
+	md, structure := contextStructure(contextURL)
+	return layoutForProperties(properties, md, structure)
+}
+
+// propertyType returns the type of a, potentially dotted, property of the structured type, if known
+func propertyType(md *Metadata, structure *CSDLStructureType, name string) string {
+	head, rest, nested := strings.Cut(name, ".")
+	for _, prop := range md.Properties(structure) {
+		if prop.Name == head {
+			if !nested {
+				return prop.Type
+			}
+			if st := md.StructureType(prop.Type); st != nil {
+				return propertyType(md, st, rest)
+			}
+			return ""
+		}
+	}
+	for _, nav := range md.NavigationProperties(structure) {
+		if nav.Name == head {
+			if !nested {
+				return nav.Type
+			}
+			if st := md.StructureType(nav.Type); st != nil {
+				return propertyType(md, st, rest)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// layoutForColumns creates the layout for a predefined set of columns, formatting the values according to their
+// types if the metadata describing them is available
+func layoutForColumns(columns []string, contextURL string) *tableLayout {
+	md, structure := contextStructure(contextURL)
+	layout := &tableLayout{}
+	for _, name := range columns {
+		format := Stringify
+		if structure != nil {
+			if typeName := propertyType(md, structure, name); typeName != "" {
+				format = formatterForType(md, typeName)
+			}
+		}
+		layout.Columns = append(layout.Columns, tableColumn{Name: name, Format: cellFormatter(format)})
+	}
+	return layout
+}
+
+// truncateCell shortens the text to the specified display width, marking it as truncated with an ellipsis
+func truncateCell(text string, width int) string {
+	if tablewriter.DisplayWidth(text) <= width {
+		return text
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range text {
+		w := tablewriter.DisplayWidth(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// wrapCell splits the text in lines no wider than the specified display width
+func wrapCell(text string, width int) string {
+	var lines []string
+	var b strings.Builder
+	used := 0
+	for _, r := range text {
+		w := tablewriter.DisplayWidth(string(r))
+		if used+w > width && used > 0 {
+			lines = append(lines, b.String())
+			b.Reset()
+			used = 0
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	lines = append(lines, b.String())
+	return strings.Join(lines, "\n")
+}
+
+// fitColumns limits the width of the widest columns such that the table fits the available width
+func fitColumns(headers []string, rows [][]string, available int) []int {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = tablewriter.DisplayWidth(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				widths[i] = max(widths[i], tablewriter.DisplayWidth(line))
+			}
+		}
+	}
+
+	// Each column takes its content's width plus 3 characters for the padding and separator, plus the closing border
+	budget := available - 3*len(headers) - 1
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if total <= budget {
+		return nil
+	}
+
+	// Find the largest width limit for which the table still fits, without going below the minimum
+	sorted := append([]int(nil), widths...)
+	sort.Ints(sorted)
+	limit := minColumnWidth
+	for candidate := sorted[len(sorted)-1]; candidate > minColumnWidth; candidate-- {
+		sum := 0
+		for _, w := range widths {
+			sum += min(w, candidate)
+		}
+		if sum <= budget {
+			limit = candidate
+			break
+		}
+	}
+	for i := range widths {
+		widths[i] = min(widths[i], limit)
+	}
+	return widths
+}
+
+func renderTable(rows []map[string]any, layout *tableLayout) error {
+	headers := make([]string, len(layout.Columns))
+	for i, column := range layout.Columns {
+		headers[i] = strings.ToUpper(column.Name)
+	}
+
+	cells := make([][]string, len(rows))
+	for r, obj := range rows {
+		row := make([]string, len(headers))
+		for i, column := range layout.Columns {
+			if val, ok := obj[column.Name]; ok {
+				row[i] = column.Format(val)
+			}
+		}
+		cells[r] = row
+	}
+
+	fitTable(headers, cells)
+
+	if layout.highlightColumn != nil {
+		for i, column := range layout.Columns {
+			if layout.highlightColumn(column.Name) {
+				headers[i] = highlight(headers[i])
+			}
+		}
+	}
+	if layout.highlightCell != nil {
+		for r, row := range cells {
+			for i, column := range layout.Columns {
+				if layout.highlightCell(r, column.Name) {
+					row[i] = highlight(row[i])
+				}
+			}
+		}
+	}
+
+	writeTable(headers, cells)
+	return nil
+}
+
+// fitTable fits the table to the terminal by truncating, or wrapping, the content of the widest columns
+func fitTable(headers []string, cells [][]string) {
+	width := TerminalWidth()
+	if width <= 0 {
+		return
+	}
+	widths := fitColumns(headers, cells, width)
+	if widths == nil {
+		return
+	}
+	fit := truncateCell
+	if viper.GetBool("wrap") {
+		fit = wrapCell
+	}
+	for i := range headers {
+		headers[i] = truncateCell(headers[i], widths[i])
+	}
+	for _, row := range cells {
+		for i := range row {
+			row[i] = fit(row[i], widths[i])
+		}
+	}
+}
+
+func writeTable(headers []string, cells [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetHeader(headers)
+	table.AppendBulk(cells)
+	table.Render()
+}
+
+// OutputGrid renders a table with the headers, and cells, as is, only fitting it to the width of the terminal
+func OutputGrid(headers []string, cells [][]string) {
+	fitTable(headers, cells)
+	writeTable(headers, cells)
+}
+
+func printArrayTable(list []any, contextURL string, columns []string) error {
+	if len(list) == 0 {
+		fmt.Println("No data.")
+		return nil
+	}
+
+	rows := flattenRows(list)
+	if len(columns) > 0 {
+		return renderTable(rows, layoutForColumns(columns, contextURL))
+	}
+
+	// Collect headers from all the, flattened, items as not all items necessarily have the same properties
+	return renderTable(rows, layoutForContext(rowProperties(rows), contextURL))
+}
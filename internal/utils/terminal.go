@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+)
+
+// IsTerminal returns true if the file, typically os.Stdout, is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// TerminalWidth returns the width, in columns, of the terminal stdout is connected to or 0 if unknown. The
+// COLUMNS environment variable, if set, takes precedence over the width reported by the terminal.
+func TerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if !IsTerminal(os.Stdout) {
+		return 0
+	}
+	return terminalWidth(os.Stdout)
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// TreeNode is a node of a tree to be output, the label being what represents the node in table format and the
+// data what represents the node in the JSON formats
+type TreeNode struct {
+	Label    string
+	Data     map[string]any
+	Children []*TreeNode
+}
+
+// treeData returns the data of the node with the data of its children, recursively, in a Children property
+func treeData(node *TreeNode) map[string]any {
+	data := make(map[string]any, len(node.Data)+1)
+	for key, val := range node.Data {
+		data[key] = val
+	}
+	if len(node.Children) > 0 {
+		children := make([]any, len(node.Children))
+		for i, child := range node.Children {
+			children[i] = treeData(child)
+		}
+		data["Children"] = children
+	}
+	return data
+}
+
+func printTreeNodes(nodes []*TreeNode, indent string) {
+	for i, node := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Println(indent + branch + node.Label)
+		printTreeNodes(node.Children, indent+next)
+	}
+}
+
+// OutputTree outputs the trees, in table format drawn as an ASCII tree, in the JSON formats as nested objects
+func OutputTree(roots []*TreeNode) error {
+	if viper.GetString("output-format") != "table" {
+		list := make([]any, len(roots))
+		for i, root := range roots {
+			list[i] = treeData(root)
+		}
+		return Output(list)
+	}
+
+	if len(roots) == 0 {
+		fmt.Println("No data.")
+		return nil
+	}
+	for _, root := range roots {
+		fmt.Println(root.Label)
+		printTreeNodes(root.Children, "")
+	}
+	return nil
+}
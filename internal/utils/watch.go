@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Fetcher retrieves the, current, response of a request whose result is to be output
+type Fetcher func() (map[string]any, error)
+
+// watchState holds the items, by key, retrieved by the previous poll
+type watchState struct {
+	items map[string]map[string]any
+	order []string
+}
+
+// OutputCollectionFrom outputs the collection returned by fetch or, in watch mode, keeps polling and outputting
+// the collection until interrupted
+func OutputCollectionFrom(fetch Fetcher, columns ...string) error {
+	if !viper.GetBool("watch") {
+		data, err := fetch()
+		if err != nil {
+			return err
+		}
+		return OutputCollection(data, columns...)
+	}
+	return watch(fetch, true, columns)
+}
+
+// OutputEntityFrom outputs the entity returned by fetch or, in watch mode, keeps polling and outputting the
+// entity until interrupted
+func OutputEntityFrom(fetch Fetcher, columns ...string) error {
+	if !viper.GetBool("watch") {
+		data, err := fetch()
+		if err != nil {
+			return err
+		}
+		return OutputEntity(data, columns...)
+	}
+	return watch(fetch, false, columns)
+}
+
+// itemKey identifies an item using its key properties, as defined by the metadata, or commonly used identifying
+// properties if the metadata isn't available, falling back to its position in the list
+func itemKey(item map[string]any, keys []string, index int) string {
+	if len(keys) > 0 {
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			val, ok := item[key]
+			if !ok {
+				break
+			}
+			parts = append(parts, Stringify(val))
+		}
+		if len(parts) == len(keys) {
+			return strings.Join(parts, ",")
+		}
+	}
+	for _, key := range []string{"ID", "Name", "@odata.id"} {
+		if val, ok := item[key]; ok {
+			return Stringify(val)
+		}
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// watchItems extracts the items, and the context URL describing them, from a response
+func watchItems(data map[string]any, collection bool) ([]any, string, error) {
+	contextURL, _ := data["@odata.context"].(string)
+	if !collection {
+		delete(data, "@odata.context")
+		return []any{data}, contextURL, nil
+	}
+	items, ok := data["value"].([]any)
+	if !ok {
+		return nil, "", errors.New("'value' not found in response")
+	}
+	return items, contextURL, nil
+}
+
+// keyProperties returns the key properties of the items described by the context URL, if known
+func keyProperties(contextURL string) []string {
+	if contextURL == "" {
+		return nil
+	}
+	md, err := MetadataForContext(contextURL)
+	if err != nil {
+		return nil
+	}
+	structure := md.ContextType(contextURL)
+	if structure == nil {
+		return nil
+	}
+	return md.Keys(structure)
+}
+
+// changedProperties returns the flattened properties whose values differ between the previous and current item
+func changedProperties(previous, current map[string]any) map[string]bool {
+	changed := make(map[string]bool)
+	for key, val := range current {
+		if old, ok := previous[key]; !ok || !reflect.DeepEqual(old, val) {
+			changed[key] = true
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changed[key] = true
+		}
+	}
+	return changed
+}
+
+func watch(fetch Fetcher, collection bool, columns []string) error {
+	interval := viper.GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval specified: %s", interval)
+	}
+
+	ctx, stop := InterruptContext()
+	defer stop()
+
+	var state *watchState
+	for {
+		data, err := fetch()
+		if err != nil {
+			// Errors, presumably transient, are reported but don't end watching
+			fmt.Fprintf(os.Stderr, "%s Error: %v\n", time.Now().Format(time.TimeOnly), err)
+		} else {
+			items, contextURL, err := watchItems(data, collection)
+			if err != nil {
+				return err
+			}
+			if state, err = outputWatch(items, contextURL, columns, state); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// outputWatch outputs the result of a poll, in table format as a table highlighting what changed, in the other
+// formats as the events representing the changes compared to the previous poll
+func outputWatch(items []any, contextURL string, columns []string, previous *watchState) (*watchState, error) {
+	keys := keyProperties(contextURL)
+	rows := flattenRows(items)
+	current := &watchState{items: make(map[string]map[string]any, len(rows))}
+	for i, row := range rows {
+		key := itemKey(row, keys, i)
+		current.items[key] = row
+		current.order = append(current.order, key)
+	}
+
+	if viper.GetString("output-format") == "table" {
+		return current, outputWatchTable(rows, contextURL, columns, current, previous)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	var events []any
+	for i, key := range current.order {
+		var old map[string]any
+		if previous != nil {
+			old = previous.items[key]
+		}
+		if old == nil {
+			events = append(events, map[string]any{"Time": now, "Event": "added", "Key": key, "Item": items[i]})
+			continue
+		}
+		changed := changedProperties(old, current.items[key])
+		if len(changed) == 0 {
+			continue
+		}
+		changes := make(map[string]any, len(changed))
+		for prop := range changed {
+			changes[prop] = map[string]any{"Old": old[prop], "New": current.items[key][prop]}
+		}
+		events = append(events, map[string]any{"Time": now, "Event": "modified", "Key": key, "Changes": changes, "Item": items[i]})
+	}
+	if previous != nil {
+		for _, key := range previous.order {
+			if _, ok := current.items[key]; !ok {
+				events = append(events, map[string]any{"Time": now, "Event": "deleted", "Key": key})
+			}
+		}
+	}
+	for _, event := range events {
+		if err := Output(event); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+func outputWatchTable(rows []map[string]any, contextURL string, columns []string, current, previous *watchState) error {
+	tty := IsTerminal(os.Stdout)
+	if tty {
+		// Redraw in place by clearing the screen and moving the cursor to the top left
+		fmt.Print("\033[H\033[2J")
+	} else if previous != nil {
+		fmt.Println()
+	}
+	fmt.Printf("Every %s: %s\n\n", viper.GetDuration("interval"), time.Now().Format(time.DateTime))
+
+	if len(rows) == 0 {
+		fmt.Println("No data.")
+		return nil
+	}
+
+	var layout *tableLayout
+	if len(columns) > 0 {
+		layout = layoutForColumns(columns, contextURL)
+	} else {
+		layout = layoutForContext(rowProperties(rows), contextURL)
+	}
+	if tty && previous != nil {
+		// Highlight the cells, and the columns, that changed since the previous poll as well as any new rows
+		changed := make([]map[string]bool, len(rows))
+		columns := make(map[string]bool)
+		for i, key := range current.order {
+			if old, ok := previous.items[key]; ok {
+				changed[i] = changedProperties(old, rows[i])
+				for prop := range changed[i] {
+					columns[prop] = true
+				}
+			}
+		}
+		layout.highlightCell = func(row int, column string) bool {
+			return changed[row] == nil || changed[row][column]
+		}
+		layout.highlightColumn = func(column string) bool {
+			return columns[column]
+		}
+	}
+	return renderTable(rows, layout)
+}